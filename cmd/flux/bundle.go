@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jaydubyaeey/flux/internal/config"
+)
+
+// cmdConfigExport writes the config, active profile and role selection to a
+// bundle file for `flux config import` on another machine.
+func cmdConfigExport(args []string) {
+	if len(args) != 1 {
+		fatalf("Usage: flux config export <file>\n")
+	}
+	if err := config.ExportBundle(args[0]); err != nil {
+		fatalf("Error: %v\n", err)
+	}
+	fmt.Printf("Exported config to %s\n", args[0])
+}
+
+// cmdConfigImport restores a bundle written by `flux config export`, asking
+// before it replaces an existing config unless --yes is given.
+func cmdConfigImport(args []string) {
+	yes := hasFlag(args, "--yes") || hasFlag(args, "-y")
+	var file string
+	for _, arg := range args {
+		if arg != "--yes" && arg != "-y" {
+			if file != "" {
+				fatalf("Usage: flux config import <file> [--yes]\n")
+			}
+			file = arg
+		}
+	}
+	if file == "" {
+		fatalf("Usage: flux config import <file> [--yes]\n")
+	}
+
+	b, err := config.ReadBundle(file)
+	if err != nil {
+		fatalf("Error: %v\n", err)
+	}
+	target := config.ImportTarget(b)
+	if _, err := os.Stat(target); err == nil && !yes {
+		if !confirm(fmt.Sprintf("Overwrite %s?", target), false) {
+			fmt.Println("Nothing imported.")
+			return
+		}
+	}
+	if err := config.ImportBundle(b); err != nil {
+		fatalf("Error importing: %v\n", err)
+	}
+	fmt.Printf("Imported config to %s\n", target)
+	if b.Profile != "" {
+		fmt.Printf("Active profile: %s\n", b.Profile)
+	}
+	if b.State != nil {
+		fmt.Println("Restored the role selection.")
+	}
+}
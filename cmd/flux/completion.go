@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jaydubyaeey/flux/internal/ansible"
+	"github.com/jaydubyaeey/flux/internal/config"
+)
+
+// Words offered by `flux completion`. Keep these in step with usage.
+var (
+	completionCommands    = []string{"run", "config", "roles", "update", "env", "doctor", "logs", "completion", "version", "help"}
+	completionConfigSubs  = []string{"show", "edit", "path", "init", "set", "get", "set-many", "restore", "use", "diff", "export-vars", "export", "import", "new-options"}
+	completionUpdateFlags = []string{"--binary", "--check", "--rollback", "--no-retry", "--stash", "--dir"}
+	completionRunFlags    = []string{
+		"--dry-run", "--safe", "--apply", "--since-last", "--tags", "--skip-tags", "--force-tags", "--retry-failed", "--var-file", "--extra-vars-file", "--set",
+		"--log-file", "--inventory", "--connection", "--limit", "--user", "--forks", "--vault-password-file",
+		"--syntax-check", "--list-tasks", "--preflight", "--print-command", "--force", "--expect-hash", "--yes", "--json", "--quiet", "--step", "--strict", "--check-packages", "--changed-only", "--post-hook", "--no-become-pass", "--config", "--no-color", "--offline", "--verbose",
+	}
+)
+
+// cmdCompletion prints a completion script for the given shell.
+func cmdCompletion(args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: flux completion [bash|zsh|fish]")
+		os.Exit(1)
+	}
+
+	roles := config.AvailableRoles()
+	if dir, err := ansible.FindAnsibleDir(); err == nil {
+		roles = config.DiscoverRoles(dir)
+	}
+	words := func(list []string) string { return strings.Join(list, " ") }
+
+	switch args[0] {
+	case "bash":
+		fmt.Printf(bashCompletion, words(roles), words(completionRunFlags),
+			words(completionConfigSubs), words(completionUpdateFlags), words(completionCommands))
+	case "zsh":
+		fmt.Printf(zshCompletion, words(completionCommands), words(roles), words(completionRunFlags),
+			words(completionConfigSubs), words(completionUpdateFlags))
+	case "fish":
+		printFishCompletion(roles)
+	default:
+		fmt.Fprintf(os.Stderr, "Unsupported shell %q (use bash, zsh or fish)\n", args[0])
+		os.Exit(1)
+	}
+}
+
+const bashCompletion = `# bash completion for flux
+_flux() {
+    local cur prev
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    prev="${COMP_WORDS[COMP_CWORD-1]}"
+
+    if [[ "$prev" == "--tags" || "$prev" == "-t" || "$prev" == "--skip-tags" ]]; then
+        # Complete the last entry of a comma-separated list
+        local prefix="" word="$cur"
+        if [[ "$cur" == *,* ]]; then
+            prefix="${cur%%,*},"
+            word="${cur##*,}"
+        fi
+        COMPREPLY=( $(compgen -P "$prefix" -W "%s" -- "$word") )
+        return
+    fi
+
+    case "${COMP_WORDS[1]}" in
+        run)        COMPREPLY=( $(compgen -W "%s" -- "$cur") ) ;;
+        config)     [[ $COMP_CWORD -eq 2 ]] && COMPREPLY=( $(compgen -W "%s" -- "$cur") ) ;;
+        update)     COMPREPLY=( $(compgen -W "%s" -- "$cur") ) ;;
+        roles)      COMPREPLY=( $(compgen -W "list --json" -- "$cur") ) ;;
+        env)        COMPREPLY=( $(compgen -W "--json" -- "$cur") ) ;;
+        logs)       COMPREPLY=( $(compgen -W "-n -f" -- "$cur") ) ;;
+        completion) COMPREPLY=( $(compgen -W "bash zsh fish" -- "$cur") ) ;;
+        *)          [[ $COMP_CWORD -eq 1 ]] && COMPREPLY=( $(compgen -W "%s" -- "$cur") ) ;;
+    esac
+}
+complete -F _flux flux
+`
+
+const zshCompletion = `#compdef flux
+_flux() {
+    if (( CURRENT == 2 )); then
+        compadd -- %s
+        return
+    fi
+    if [[ ${words[CURRENT-1]} == (--tags|-t|--skip-tags) ]]; then
+        _values -s , 'role' %s
+        return
+    fi
+    case ${words[2]} in
+        run)        compadd -- %s ;;
+        config)     (( CURRENT == 3 )) && compadd -- %s ;;
+        update)     compadd -- %s ;;
+        roles)      compadd -- list --json ;;
+        env)        compadd -- --json ;;
+        logs)       compadd -- -n -f ;;
+        completion) compadd -- bash zsh fish ;;
+    esac
+}
+
+if [[ "${funcstack[1]}" == "_flux" ]]; then
+    _flux "$@"
+else
+    compdef _flux flux
+fi
+`
+
+func printFishCompletion(roles []string) {
+	fmt.Println("# fish completion for flux")
+	fmt.Println("complete -c flux -f")
+	fmt.Printf("complete -c flux -n __fish_use_subcommand -a '%s'\n", strings.Join(completionCommands, " "))
+	fmt.Printf("complete -c flux -n '__fish_seen_subcommand_from config' -a '%s'\n", strings.Join(completionConfigSubs, " "))
+	fmt.Println("complete -c flux -n '__fish_seen_subcommand_from roles' -a list")
+	fmt.Println("complete -c flux -n '__fish_seen_subcommand_from completion' -a 'bash zsh fish'")
+
+	// Flags that take a value; --tags also gets the role names
+	valued := map[string]bool{
+		"--var-file": true, "--extra-vars-file": true, "--set": true, "--log-file": true,
+		"--inventory": true, "--connection": true, "--limit": true, "--user": true, "--forks": true,
+		"--vault-password-file": true, "--expect-hash": true, "--config": true, "--dir": true,
+	}
+	flagLine := func(sub, flag string) {
+		name := strings.TrimPrefix(flag, "--")
+		line := fmt.Sprintf("complete -c flux -n '__fish_seen_subcommand_from %s' -l %s", sub, name)
+		switch {
+		case flag == "--tags", flag == "--skip-tags":
+			line += fmt.Sprintf(" -x -a '%s'", strings.Join(roles, " "))
+		case valued[flag]:
+			line += " -r -F"
+		}
+		fmt.Println(line)
+	}
+	for _, f := range completionRunFlags {
+		flagLine("run", f)
+	}
+	for _, f := range completionUpdateFlags {
+		flagLine("update", f)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/jaydubyaeey/flux/internal/config"
+)
+
+var (
+	diffOldStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#F87171"))
+	diffNewStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#34D399"))
+)
+
+// cmdConfigDiff prints the fields that differ between two configs:
+//
+//	flux config diff              defaults → current config
+//	flux config diff <p>          current config → profile p
+//	flux config diff <p1> <p2>    profile p1 → profile p2
+func cmdConfigDiff(args []string) {
+	var a, b *config.Config
+	var aName, bName string
+	switch len(args) {
+	case 0:
+		a, aName = config.DefaultConfig(), "defaults"
+		b, bName = mustLoadConfig(), "current"
+	case 1:
+		a, aName = mustLoadConfig(), "current"
+		b, bName = mustLoadProfile(args[0]), args[0]
+	case 2:
+		a, aName = mustLoadProfile(args[0]), args[0]
+		b, bName = mustLoadProfile(args[1]), args[1]
+	default:
+		fmt.Fprintln(os.Stderr, "Usage: flux config diff [profile [profile]]")
+		os.Exit(1)
+	}
+
+	diffs := config.Diff(a, b)
+	if len(diffs) == 0 {
+		fmt.Printf("No differences between %s and %s.\n", aName, bName)
+		return
+	}
+	fmt.Printf("--- %s\n+++ %s\n", aName, bName)
+	printDiff(diffs)
+}
+
+// printDiff prints each differing field with its old and new value.
+func printDiff(diffs []config.FieldDiff) {
+	for _, d := range diffs {
+		fmt.Println(d.Key + ":")
+		fmt.Println(diffOldStyle.Render("  - " + orEmpty(d.Old)))
+		fmt.Println(diffNewStyle.Render("  + " + orEmpty(d.New)))
+	}
+}
+
+func orEmpty(s string) string {
+	if s == "" {
+		return "(empty)"
+	}
+	return s
+}
+
+func mustLoadConfig() *config.Config {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "No config found. Run 'flux' to create one.\n")
+		os.Exit(1)
+	}
+	return cfg
+}
+
+func mustLoadProfile(name string) *config.Config {
+	cfg, err := config.LoadProfile(name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return cfg
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/jaydubyaeey/flux/internal/ansible"
+	"github.com/jaydubyaeey/flux/internal/config"
+	"github.com/jaydubyaeey/flux/internal/platform"
+	"github.com/jaydubyaeey/flux/internal/updater"
+)
+
+// checkStatus is the outcome of a single doctor check.
+type checkStatus int
+
+const (
+	checkOK checkStatus = iota
+	checkWarn
+	checkFail
+)
+
+// doctorCheck is one line of `flux doctor` output.
+type doctorCheck struct {
+	name   string
+	status checkStatus
+	detail string
+}
+
+// cmdDoctor checks that flux can run on this machine and prints what it
+// found. It exits non-zero if any check fails outright.
+func cmdDoctor(args []string) {
+	checks := []doctorCheck{
+		checkConfig(),
+		checkWSL(),
+		checkSudo(),
+		checkAnsiblePlaybook(),
+		checkOffline(),
+		checkInstall(),
+	}
+	checks = append(checks, checkAnsibleDir()...)
+
+	failed := false
+	for _, c := range checks {
+		mark := "✓"
+		switch c.status {
+		case checkWarn:
+			mark = "!"
+		case checkFail:
+			mark = "✗"
+			failed = true
+		}
+		fmt.Printf("%s %-18s %s\n", mark, c.name, c.detail)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func checkConfig() doctorCheck {
+	c := doctorCheck{name: "config"}
+	cfg, err := config.Load()
+	switch {
+	case err != nil && !config.Exists():
+		c.status, c.detail = checkWarn, fmt.Sprintf("no config at %s yet (run 'flux' to create one)", config.FilePath())
+	case err != nil:
+		c.status, c.detail = checkFail, err.Error()
+	default:
+		if err := cfg.Validate(); err != nil {
+			c.status, c.detail = checkFail, err.Error()
+		} else {
+			c.detail = fmt.Sprintf("%s (%s)", config.FilePath(), config.FilePathSource())
+		}
+	}
+	return c
+}
+
+func checkInstall() doctorCheck {
+	dir, method, err := updater.DetectInstall()
+	if err != nil {
+		return doctorCheck{name: "install", status: checkWarn, detail: "flux update won't work: " + err.Error()}
+	}
+	return doctorCheck{name: "install", detail: fmt.Sprintf("%s (%s), binary %s", dir, method, updater.BinPath())}
+}
+
+func checkWSL() doctorCheck {
+	if platform.IsWSL() {
+		return doctorCheck{name: "wsl", detail: "running under WSL"}
+	}
+	return doctorCheck{name: "wsl", status: checkWarn, detail: "not running under WSL; flux run needs --force"}
+}
+
+func checkSudo() doctorCheck {
+	mode := platform.SudoMode()
+	if mode == "none" {
+		return doctorCheck{name: "sudo", status: checkFail, detail: "not root and sudo not found"}
+	}
+	if mode == "sudo" && platform.PasswordlessSudo() {
+		mode = "sudo (passwordless)"
+	}
+	return doctorCheck{name: "sudo", detail: mode}
+}
+
+func checkAnsiblePlaybook() doctorCheck {
+	path, err := exec.LookPath("ansible-playbook")
+	if err != nil {
+		return doctorCheck{name: "ansible-playbook", status: checkWarn, detail: "not installed (flux run installs it)"}
+	}
+	v, err := ansible.Version()
+	if err != nil {
+		return doctorCheck{name: "ansible-playbook", status: checkWarn, detail: fmt.Sprintf("%s (%v)", path, err)}
+	}
+	if !ansible.VersionAtLeast(v, ansible.MinVersion) {
+		return doctorCheck{name: "ansible-playbook", status: checkWarn,
+			detail: fmt.Sprintf("%s is %s; roles need %s or newer", path, v, ansible.MinVersion)}
+	}
+	return doctorCheck{name: "ansible-playbook", detail: fmt.Sprintf("%s (%s)", path, v)}
+}
+
+// checkOffline reports whether `flux --offline` could run: it needs a
+// usable Ansible already installed since it won't download one.
+func checkOffline() doctorCheck {
+	c := doctorCheck{name: "offline mode"}
+	v, err := ansible.Version()
+	switch {
+	case err != nil:
+		c.status = checkWarn
+		c.detail = "unavailable: ansible-playbook must be installed first"
+	case !ansible.VersionAtLeast(v, ansible.MinVersion):
+		c.status = checkWarn
+		c.detail = fmt.Sprintf("unavailable: ansible %s is older than %s", v, ansible.MinVersion)
+	default:
+		c.detail = "ready (roles that download toolchains will be skipped)"
+	}
+	return c
+}
+
+func checkAnsibleDir() []doctorCheck {
+	dir, err := ansible.FindAnsibleDir()
+	if err != nil {
+		return []doctorCheck{{name: "ansible dir", status: checkFail, detail: err.Error()}}
+	}
+	checks := []doctorCheck{{name: "ansible dir", detail: dir}}
+	hash, err := ansible.HashAnsibleDir(dir)
+	if err != nil {
+		return append(checks, doctorCheck{name: "ansible hash", status: checkFail, detail: err.Error()})
+	}
+	return append(checks, doctorCheck{name: "ansible hash", detail: hash})
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jaydubyaeey/flux/internal/ansible"
+	"github.com/jaydubyaeey/flux/internal/config"
+	"github.com/jaydubyaeey/flux/internal/platform"
+	"github.com/jaydubyaeey/flux/internal/updater"
+)
+
+// envInfo is the runtime environment flux resolved, as printed by `flux env`.
+type envInfo struct {
+	ConfigPath     string `json:"config_path"`
+	ConfigSource   string `json:"config_source"`
+	ConfigExists   bool   `json:"config_exists"`
+	AnsibleDir     string `json:"ansible_dir"`
+	InstallDir     string `json:"install_dir"`
+	PackageManager string `json:"package_manager"`
+	SudoMode       string `json:"sudo_mode"`
+	WSL            bool   `json:"wsl"`
+}
+
+// cmdEnv prints where flux looks for things and what it detected.
+func cmdEnv(args []string) {
+	writeEnv(os.Stdout, collectEnv(), hasFlag(args, "--json"))
+}
+
+// collectEnv resolves the paths and detected values `flux env` reports.
+func collectEnv() envInfo {
+	info := envInfo{
+		ConfigPath:     config.FilePath(),
+		ConfigSource:   config.FilePathSource(),
+		ConfigExists:   config.Exists(),
+		InstallDir:     updater.InstallDir(),
+		PackageManager: platform.PackageManager(),
+		SudoMode:       platform.SudoMode(),
+		WSL:            platform.IsWSL(),
+	}
+	if dir, err := ansible.FindAnsibleDir(); err == nil {
+		info.AnsibleDir = dir
+	}
+	if dir, _, err := updater.DetectInstall(); err == nil {
+		info.InstallDir = dir
+	}
+	return info
+}
+
+// writeEnv prints info to w as aligned text, or as JSON with jsonOut.
+func writeEnv(w io.Writer, info envInfo, jsonOut bool) {
+	if jsonOut {
+		out, _ := json.MarshalIndent(info, "", "  ")
+		fmt.Fprintln(w, string(out))
+		return
+	}
+
+	orNone := func(s string) string {
+		if s == "" {
+			return "(not found)"
+		}
+		return s
+	}
+	fmt.Fprintf(w, "config:          %s (%s)\n", info.ConfigPath, info.ConfigSource)
+	fmt.Fprintf(w, "config exists:   %t\n", info.ConfigExists)
+	fmt.Fprintf(w, "ansible dir:     %s\n", orNone(info.AnsibleDir))
+	fmt.Fprintf(w, "install dir:     %s\n", info.InstallDir)
+	fmt.Fprintf(w, "package manager: %s\n", orNone(info.PackageManager))
+	fmt.Fprintf(w, "sudo mode:       %s\n", info.SudoMode)
+	fmt.Fprintf(w, "wsl:             %t\n", info.WSL)
+}
+
+// hasFlag reports whether flag appears in args.
+func hasFlag(args []string, flag string) bool {
+	for _, a := range args {
+		if a == flag {
+			return true
+		}
+	}
+	return false
+}
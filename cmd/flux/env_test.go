@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jaydubyaeey/flux/internal/config"
+	"github.com/jaydubyaeey/flux/internal/platform"
+)
+
+func TestEnvReflectsOverrides(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	installDir := filepath.Join(home, "src", "flux")
+	t.Setenv("FLUX_INSTALL_DIR", installDir)
+
+	tests := []struct {
+		name       string
+		flag, env  string
+		wantPath   string
+		wantSource string
+	}{
+		{"default", "", "", filepath.Join(home, ".config", "flux", "config.yaml"), "default"},
+		{"FLUX_CONFIG", "", filepath.Join(home, "env.yaml"), filepath.Join(home, "env.yaml"), "env"},
+		{"--config beats FLUX_CONFIG", filepath.Join(home, "flag.yaml"), filepath.Join(home, "env.yaml"), filepath.Join(home, "flag.yaml"), "flag"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("FLUX_CONFIG", tt.env)
+			config.SetFilePath(tt.flag)
+			t.Cleanup(func() { config.SetFilePath("") })
+
+			info := collectEnv()
+			if info.ConfigPath != tt.wantPath || info.ConfigSource != tt.wantSource {
+				t.Errorf("config = %s (%s), want %s (%s)", info.ConfigPath, info.ConfigSource, tt.wantPath, tt.wantSource)
+			}
+			if info.InstallDir != installDir {
+				t.Errorf("install dir = %s, want FLUX_INSTALL_DIR %s", info.InstallDir, installDir)
+			}
+			if info.PackageManager != platform.PackageManager() || info.SudoMode != platform.SudoMode() || info.WSL != platform.IsWSL() {
+				t.Errorf("detected %q/%q/%v, want %q/%q/%v", info.PackageManager, info.SudoMode, info.WSL,
+					platform.PackageManager(), platform.SudoMode(), platform.IsWSL())
+			}
+
+			var text bytes.Buffer
+			writeEnv(&text, info, false)
+			for _, want := range []string{tt.wantPath + " (" + tt.wantSource + ")", "install dir:     " + installDir, "sudo mode:       " + info.SudoMode} {
+				if !strings.Contains(text.String(), want) {
+					t.Errorf("text output missing %q:\n%s", want, text.String())
+				}
+			}
+
+			var out bytes.Buffer
+			writeEnv(&out, info, true)
+			var decoded envInfo
+			if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+				t.Fatalf("--json output isn't JSON: %v\n%s", err, out.String())
+			}
+			if decoded != info {
+				t.Errorf("--json round trip = %+v, want %+v", decoded, info)
+			}
+		})
+	}
+}
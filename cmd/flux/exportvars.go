@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/jaydubyaeey/flux/internal/config"
+)
+
+// cmdConfigExportVars prints the extra vars `flux run` would pass to
+// ansible: the config-derived vars with the extra-vars file merged over
+// them. --json (the default) is exactly the --extra-vars payload, --yaml
+// suits a vars file, and --env prints FLUX_VAR_<name>=<value> lines that
+// can be sourced by a shell.
+func cmdConfigExportVars(args []string) {
+	format := "json"
+	for _, arg := range args {
+		switch arg {
+		case "--json", "--yaml", "--env":
+			format = strings.TrimPrefix(arg, "--")
+		default:
+			fatalf("Usage: flux config export-vars [--json|--yaml|--env]\n")
+		}
+	}
+
+	fileVars, err := config.LoadExtraVars("")
+	if err != nil {
+		fatalf("Error reading extra-vars file: %v\n", err)
+	}
+	vars := config.MergeVars(mustLoadConfig().ToExtraVars(), fileVars)
+
+	switch format {
+	case "json":
+		out, err := json.Marshal(vars)
+		if err != nil {
+			fatalf("Error: %v\n", err)
+		}
+		fmt.Println(string(out))
+	case "yaml":
+		out, err := yaml.Marshal(vars)
+		if err != nil {
+			fatalf("Error: %v\n", err)
+		}
+		fmt.Print(string(out))
+	case "env":
+		keys := make([]string, 0, len(vars))
+		for k := range vars {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Printf("FLUX_VAR_%s=%s\n", k, shellQuote(envValue(vars[k])))
+		}
+	}
+}
+
+// envValue renders a var for an environment line: strings as is, anything
+// else (booleans, lists) as JSON.
+func envValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return ""
+	}
+	return string(out)
+}
+
+// shellQuote wraps s in single quotes for POSIX shells.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
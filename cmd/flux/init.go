@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/jaydubyaeey/flux/internal/config"
+)
+
+// initFlagAliases maps short flag names to config keys.
+var initFlagAliases = map[string]string{
+	"shell": "default_shell",
+}
+
+// cmdConfigInit builds a config from defaults plus flags and saves it without
+// prompting. Every config key is accepted as --key-name; booleans are set with
+// --key-name / --no-key-name, and install_X booleans also accept --X / --no-X.
+func cmdConfigInit(args []string) {
+	if hasFlag(args, "--help") || hasFlag(args, "-h") {
+		printInitFlags()
+		return
+	}
+
+	cfg := config.DefaultConfig()
+	var force bool
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--force" {
+			force = true
+			continue
+		}
+		if !strings.HasPrefix(arg, "--") {
+			fatalf("Unexpected argument %q (see flux config init --help)\n", arg)
+		}
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		key, negated, ok := initFlagKey(name)
+		if !ok {
+			fatalf("Unknown flag --%s (see flux config init --help)\n", name)
+		}
+
+		switch {
+		case config.IsBoolField(key):
+			if !hasValue {
+				value = config.BoolStr(!negated)
+			} else if negated {
+				fatalf("--%s does not take a value\n", name)
+			}
+		case negated:
+			fatalf("Unknown flag --%s (see flux config init --help)\n", name)
+		case !hasValue:
+			if i+1 >= len(args) {
+				fatalf("--%s requires a value\n", name)
+			}
+			i++
+			value = args[i]
+		}
+		if err := cfg.SetField(key, value); err != nil {
+			fatalf("Error: %v\n", err)
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		fatalf("Invalid config: %v\nNothing was written.\n", err)
+	}
+	if config.Exists() && !force {
+		fatalf("Config already exists at %s (use --force to overwrite)\n", config.FilePath())
+	}
+	if err := config.Save(cfg); err != nil {
+		fatalf("Error saving config: %v\n", err)
+	}
+	fmt.Printf("Config saved to %s\n", config.FilePath())
+}
+
+// initFlagKey resolves a flag name (without leading dashes) to a config key,
+// reporting whether it was given in its --no- form.
+func initFlagKey(name string) (key string, negated, ok bool) {
+	base, negated := strings.CutPrefix(name, "no-")
+	key = strings.ReplaceAll(base, "-", "_")
+	if alias, found := initFlagAliases[base]; found {
+		key = alias
+	}
+	for _, k := range config.FieldKeys() {
+		if k == key {
+			return key, negated, true
+		}
+	}
+	if config.IsBoolField("install_" + key) {
+		return "install_" + key, negated, true
+	}
+	return "", false, false
+}
+
+func printInitFlags() {
+	fmt.Println("Usage: flux config init [--force] [flags]")
+	fmt.Println()
+	fmt.Println("Creates a config from the defaults plus these flags:")
+	for _, key := range config.FieldKeys() {
+		flag := "--" + strings.ReplaceAll(key, "_", "-")
+		if config.IsBoolField(key) {
+			fmt.Printf("  %s, --no-%s\n", flag, strings.TrimPrefix(flag, "--"))
+		} else {
+			fmt.Printf("  %s <value>\n", flag)
+		}
+	}
+	fmt.Println()
+	fmt.Println("List values are comma-separated. --shell is short for --default-shell,")
+	fmt.Println("and --install-X/--no-install-X may be written --X/--no-X.")
+}
+
+func fatalf(format string, a ...interface{}) {
+	slog.Error(strings.TrimSpace(fmt.Sprintf(format, a...)))
+	fmt.Fprintf(os.Stderr, format, a...)
+	os.Exit(1)
+}
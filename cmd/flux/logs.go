@@ -0,0 +1,98 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jaydubyaeey/flux/internal/ansible"
+	"github.com/jaydubyaeey/flux/internal/config"
+	"github.com/jaydubyaeey/flux/internal/logging"
+)
+
+// cmdLogs prints the tail of flux's log, and with -f keeps printing lines as
+// they're written, like tail -f.
+func cmdLogs(args []string) {
+	fs := flag.NewFlagSet("logs", flag.ExitOnError)
+	n := fs.Int("n", 50, "number of lines to print")
+	follow := fs.Bool("f", false, "keep printing new lines")
+	fs.Parse(args)
+
+	path := filepath.Join(config.LogDir(), logging.FileName)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		fmt.Printf("No log yet (%s)\n", path)
+		if !*follow {
+			return
+		}
+	} else if err != nil {
+		fatalf("Error: %v\n", err)
+	}
+	fmt.Print(lastLines(string(data), *n))
+
+	if *follow {
+		followLog(path, int64(len(data)))
+	}
+}
+
+// lastLines returns the last n lines of s.
+func lastLines(s string, n int) string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if n < len(lines) {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "")
+}
+
+// followLog polls path and prints whatever is written past offset. When the
+// file shrinks it has been rotated, so the new file is printed from the top.
+func followLog(path string, offset int64) {
+	for range time.Tick(500 * time.Millisecond) {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if info.Size() < offset {
+			offset = 0
+		}
+		if info.Size() == offset {
+			continue
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		if _, err := f.Seek(offset, io.SeekStart); err == nil {
+			n, _ := io.Copy(os.Stdout, f)
+			offset += n
+		}
+		f.Close()
+	}
+}
+
+// redactArgs returns args as they're logged: values of key=value arguments
+// (--set, config set-many) and config set whose key looks secret are masked.
+func redactArgs(args []string) []string {
+	shown := make([]string, len(args))
+	copy(shown, args)
+	for i, arg := range shown {
+		before, pair, ok := strings.Cut(arg, "--set=")
+		if !ok || before != "" {
+			pair = arg
+		}
+		if k, _, ok := strings.Cut(pair, "="); ok && ansible.IsSecretKey(k) {
+			shown[i] = strings.TrimSuffix(arg, pair) + k + "=***"
+		}
+	}
+	if len(shown) >= 4 && shown[0] == "config" && shown[1] == "set" && ansible.IsSecretKey(shown[2]) {
+		shown[3] = "***"
+	}
+	return shown
+}
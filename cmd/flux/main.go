@@ -1,34 +1,86 @@
 package main
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
+	"os/exec"
+	"strings"
 
+	"github.com/jaydubyaeey/flux/internal/ansible"
 	"github.com/jaydubyaeey/flux/internal/config"
+	"github.com/jaydubyaeey/flux/internal/logging"
+	"github.com/jaydubyaeey/flux/internal/platform"
 	"github.com/jaydubyaeey/flux/internal/tui"
 	"github.com/jaydubyaeey/flux/internal/updater"
 )
 
-const version = "0.1.0"
-
 const usage = `flux - Bootstrap and configure your WSL instance
 
 Usage:
   flux                            Launch interactive TUI
-  flux run [--dry-run] [--tags t] Run setup playbooks
+  flux run [flags]                Run setup playbooks
   flux config show                Show current configuration
-  flux config edit                Re-run interactive config prompts
+  flux config edit [--yes]        Re-run interactive config prompts
+                                  (shows the changes and asks before saving)
+  flux config edit --only-missing Only prompt for settings that are still empty
   flux config path                Print config file path
-  flux update                     Pull latest changes and rebuild
-  flux version                    Print version
+  flux config init [flags]        Create a config from flags, without prompts
+                                  (see flux config init --help)
+  flux config set <key> <value>   Set one config value (lists comma-separated)
+  flux config get <key>           Print one config value
+  flux config set-many k=v ...    Set several config values at once
+  flux config new-options         List options added since the config was saved
+  flux config restore             Restore the config saved before the last edit
+  flux config use [name]          Switch profile (no name: list profiles)
+  flux config diff [p1 [p2]]      Show settings that differ from the defaults
+                                  (or between the current config and profiles)
+  flux config export-vars         Print the extra vars passed to ansible
+                                  [--json (default)|--yaml|--env]
+  flux config export <file>       Save config, profile and role selection to one file
+  flux config import <file>       Restore a file from export (asks before overwriting;
+                                  --yes to skip)
+  flux roles list [--json]        List available role tags
+  flux update [--binary|--check]  Pull latest changes and rebuild
+                                  (--binary: download a prebuilt release,
+                                   --check: only report available updates)
+  flux update --rollback          Restore the binary from before the last update
+  flux update --no-retry          Don't retry git fetch/pull on network errors
+  flux update --stash             Stash local changes in the install dir while updating
+  flux update --dir <path>        Update the clone at <path> (default FLUX_INSTALL_DIR
+                                  or ~/.local/share/flux; binary: FLUX_BIN_PATH)
+  flux env [--json]               Show resolved paths and detected environment
+  flux doctor                     Check that flux can run on this machine
+  flux logs [-n N] [-f]           Print the last N lines of flux's log (default 50;
+                                  -f: keep printing new lines)
+  flux completion <shell>         Print a bash, zsh or fish completion script
+  flux version [--full]           Print the flux and ansible versions
+                                  (--full: also commit, build date, Go
+                                   version and install dir, for bug reports)
   flux help                       Show this help message
 
-Flags:
-  --dry-run     Run Ansible in check mode (no changes applied)
-  --tags <t>    Comma-separated list of role tags to run
-`
+Global flags:
+  --config <path>       Use this config file (also via FLUX_CONFIG)
+  --no-color            Plain output without colors (also via NO_COLOR)
+  --offline             Don't download anything: use the installed Ansible,
+                        pass offline=true so roles skip downloads, and
+                        disable flux update
+  --verbose             Also print flux's log lines to stderr
+
+` + runFlagsHelp
 
 func main() {
+	parseGlobalFlags()
+
+	// The TUI owns the terminal, so it only ever logs to the file
+	_ = logging.Setup(config.LogDir(), verbose && len(os.Args) >= 2)
+	if len(os.Args) < 2 || os.Args[1] != "logs" {
+		slog.Info("command", "args", redactArgs(os.Args[1:]))
+		slog.Debug("config", "path", config.FilePath(), "source", config.FilePathSource())
+	}
+
 	if len(os.Args) < 2 {
 		// No args — launch TUI
 		tui.Run()
@@ -37,20 +89,27 @@ func main() {
 
 	switch os.Args[1] {
 	case "run":
-		cmdRun()
+		cmdRun(os.Args[2:])
 	case "config":
 		if len(os.Args) < 3 {
-			fmt.Println("Usage: flux config [show|edit|path]")
+			fmt.Println(configUsage())
 			os.Exit(1)
 		}
-		cmdConfig(os.Args[2])
+		cmdConfig(os.Args[2], os.Args[3:])
+	case "roles":
+		cmdRoles(os.Args[2:])
+	case "env":
+		cmdEnv(os.Args[2:])
+	case "completion":
+		cmdCompletion(os.Args[2:])
+	case "doctor":
+		cmdDoctor(os.Args[2:])
+	case "logs":
+		cmdLogs(os.Args[2:])
 	case "update":
-		if err := updater.Update(); err != nil {
-			fmt.Fprintf(os.Stderr, "Update failed: %v\n", err)
-			os.Exit(1)
-		}
+		cmdUpdate(os.Args[2:])
 	case "version", "--version", "-v":
-		fmt.Printf("flux %s\n", version)
+		cmdVersion(os.Args[2:])
 	case "help", "--help", "-h":
 		fmt.Print(usage)
 	default:
@@ -60,31 +119,509 @@ func main() {
 	}
 }
 
-func cmdRun() {
+func cmdUpdate(args []string) {
+	if dir := flagValue(args, "--dir"); dir != "" {
+		updater.SetInstallDir(dir)
+	}
+	if hasFlag(args, "--no-retry") {
+		updater.DisableRetry()
+	}
+	if hasFlag(args, "--stash") {
+		updater.StashLocalChanges()
+	}
+
+	if hasFlag(args, "--rollback") {
+		if err := updater.Rollback(); err != nil {
+			fmt.Fprintf(os.Stderr, "Rollback failed: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	check := hasFlag(args, "--check")
+	if check || (!hasFlag(args, "--binary") && isTerminal(os.Stdin)) {
+		behind, subject, err := updater.CheckForUpdate()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Update check failed: %v\n", err)
+			os.Exit(1)
+		}
+		if behind == 0 {
+			fmt.Println("✓ Already up to date")
+			return
+		}
+		fmt.Printf("%d commit(s) behind: %s\n", behind, subject)
+		if check || !confirm("Update now?", true) {
+			return
+		}
+	}
+
+	unlock, err := config.Lock()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if hasFlag(args, "--binary") {
+		err = updater.UpdateFromRelease("")
+		if errors.Is(err, updater.ErrNoReleaseAsset) {
+			fmt.Printf("Note: %v; falling back to git + rebuild.\n", err)
+			err = updater.Update()
+		}
+	} else {
+		err = updater.Update()
+	}
+	unlock()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Update failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Only the new binary knows its defaults, so let it do the comparing
+	notes := exec.Command(updater.BinPath(), "--config", config.FilePath(), "config", "new-options", "--quiet")
+	notes.Stdout, notes.Stderr = os.Stdout, os.Stderr
+	_ = notes.Run()
+}
+
+// cmdConfigNewOptions lists config options the saved file doesn't have yet.
+// With quiet, nothing is printed when there are none or no config exists.
+func cmdConfigNewOptions(quiet bool) {
+	opts, err := config.NewOptions()
+	if err != nil {
+		if quiet {
+			return
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(opts) == 0 {
+		if !quiet {
+			fmt.Println("✓ Your config has every available option")
+		}
+		return
+	}
+	fmt.Println("New options available:")
+	for _, o := range opts {
+		def := o.Default
+		if def == "" {
+			def = `""`
+		}
+		fmt.Printf("  %s (default %s)\n", o.Key, def)
+	}
+	fmt.Printf("They count as empty/false until set, e.g. 'flux config set %s %s'.\n", opts[0].Key, opts[0].Default)
+}
+
+// isTerminal reports whether f is an interactive terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// checkExtraPackages warns about extra_packages that apt can't find and asks
+// whether to go ahead anyway (assumed yes with --yes). Aborting exits.
+func checkExtraPackages(pkgs []string, yes bool) {
+	if len(pkgs) == 0 {
+		return
+	}
+	missing, err := platform.MissingAptPackages(pkgs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not check extra_packages: %v\n", err)
+		return
+	}
+	if len(missing) == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Warning: these extra_packages weren't found in the apt cache: %s\n", strings.Join(missing, ", "))
+	fmt.Fprintln(os.Stderr, "Check for typos, or run 'sudo apt-get update' if the cache is stale.")
+	if !yes && !confirm("Continue anyway?", false) {
+		fmt.Println("Aborted.")
+		os.Exit(1)
+	}
+}
+
+// confirm asks a yes/no question on stdin, returning def on empty input and
+// false for anything config.ParseBoolStrict doesn't accept.
+func confirm(question string, def bool) bool {
+	hint := "[y/N]"
+	if def {
+		hint = "[Y/n]"
+	}
+	fmt.Printf("%s %s: ", question, hint)
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	if strings.TrimSpace(line) == "" {
+		return def
+	}
+	yes, ok := config.ParseBoolStrict(line)
+	return ok && yes
+}
+
+// cmdSyntaxCheck validates the playbook without running it. It doesn't need
+// a config, so it runs before LoadOrCreate would prompt for one.
+func cmdSyntaxCheck(opts ansible.RunOptions) {
+	if err := ansible.SyntaxCheck(opts); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("✓ Playbook syntax OK")
+}
+
+// cmdPreflight reports whether there is enough disk space, memory and
+// network access for a run with the current config and roles. It exits 1
+// if anything looks likely to fail.
+func cmdPreflight(tags, skipTags string) {
+	cfg := mustLoadConfig()
+	checks := "memory and download hosts"
+	if ansible.Offline() {
+		checks = "memory (offline: not probing download hosts)"
+	}
+	fmt.Printf("Checking disk space (about %.1f GB needed), %s...\n", float64(cfg.EstimatedDiskNeed())/(1<<30), checks)
+	warnings := config.PreflightCheck(cfg, ansible.Offline())
+	if w := config.MemoryCheck(cfg, config.RunRoles(mustFindAnsibleDir(), tags, skipTags)); w != nil {
+		warnings = append([]config.Warning{*w}, warnings...)
+	}
+	if len(warnings) == 0 {
+		fmt.Println("✓ Ready to run")
+		return
+	}
+	for _, w := range warnings {
+		fmt.Printf("! %-8s %s\n", w.Check, w.Message)
+	}
+	os.Exit(1)
+}
+
+// checkRunMemory stops a run whose roles need more memory than is free,
+// unless force is set, in which case it only warns.
+func checkRunMemory(cfg *config.Config, tags, skipTags string, force bool) {
+	w := config.MemoryCheck(cfg, config.RunRoles(mustFindAnsibleDir(), tags, skipTags))
+	if w == nil {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Warning: %s.\n%s\n", w.Message, ansible.MemoryHint)
+	if !force {
+		fmt.Fprintln(os.Stderr, "Re-run with --force to continue anyway.")
+		os.Exit(1)
+	}
+}
+
+// retryFailedTags returns the roles recorded by the last failed apply as a
+// --tags value for --retry-failed, exiting when there is nothing to retry.
+func retryFailedTags(f *runFlags) string {
+	if f.tags.String() != "" {
+		fatalf("Error: --retry-failed picks the roles itself; don't combine it with --tags\n")
+	}
+	st, err := config.LoadState()
+	if err != nil {
+		fatalf("Error reading %s: %v\n", config.StatePath(), err)
+	}
+	if len(st.FailedRoles) == 0 {
+		fmt.Println("Nothing to retry: the last apply didn't leave any roles undone.")
+		os.Exit(0)
+	}
+	if !f.quiet && !f.json {
+		fmt.Printf("Retrying: %s\n", strings.Join(st.FailedRoles, ", "))
+	}
+	return strings.Join(st.FailedRoles, ",")
+}
+
+// printPlaybookCommand prints the ansible-playbook command `flux run` would
+// execute with these flags, quoted for pasting into a shell. The vars and
+// skip-tags are resolved the same way RunPlaybookCLI resolves them.
+func printPlaybookCommand(cfg *config.Config, tags, skipTags string, f *runFlags, overrides map[string]interface{}) {
+	dir := mustFindAnsibleDir()
+	skipTags = cfg.RunSkipTags(skipTags, f.dryRun)
+	vaultPassFile := f.vaultPassFile
+	if vaultPassFile == "" {
+		vaultPassFile = cfg.VaultPasswordFile
+	}
+	args, err := ansible.PlaybookCommand(ansible.RunOptions{
+		AnsibleDir:    dir,
+		Inventory:     f.inventory,
+		Connection:    f.connection,
+		Limit:         f.limit,
+		RemoteUser:    f.remoteUser,
+		ExtraVars:     config.MergeVars(cfg.ToExtraVars(), overrides),
+		Tags:          tags,
+		SkipTags:      skipTags,
+		DryRun:        f.dryRun,
+		Step:          f.step,
+		Verbosity:     f.verbosity,
+		Forks:         cfg.Forks,
+		VaultPassFile: vaultPassFile,
+		NoBecomePass:  cfg.NoBecomePass || ((f.connection == "" || f.connection == "local") && platform.PasswordlessSudo()),
+		Passthrough:   f.passthrough,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(ansible.ShellCommand(args))
+}
+
+// inspectOptions are the RunOptions for --syntax-check and --list-tasks:
+// the run's targets, tags and passthrough flags, without the config.
+func (f *runFlags) inspectOptions(tags, skipTags string) ansible.RunOptions {
+	return ansible.RunOptions{
+		AnsibleDir:  mustFindAnsibleDir(),
+		Inventory:   f.inventory,
+		Connection:  f.connection,
+		Limit:       f.limit,
+		RemoteUser:  f.remoteUser,
+		Tags:        tags,
+		SkipTags:    skipTags,
+		Passthrough: f.passthrough,
+	}
+}
+
+// cmdListTasks prints the tasks the run opts describes would execute.
+func cmdListTasks(opts ansible.RunOptions) {
+	tasks, err := ansible.ListTasks(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	if len(tasks) == 0 {
+		fmt.Println("No tasks match the selected tags.")
+		return
+	}
+	for _, t := range tasks {
+		fmt.Println(t)
+	}
+}
+
+// verifyAnsibleHash exits unless the ansible directory hashes to want, so a
+// partial or modified checkout is never run.
+func verifyAnsibleHash(want string) {
+	dir := mustFindAnsibleDir()
+	got, err := ansible.HashAnsibleDir(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if !strings.EqualFold(got, strings.TrimSpace(want)) {
+		fmt.Fprintf(os.Stderr, "Ansible directory %s does not match the expected hash\n  expected: %s\n  actual:   %s\n", dir, want, got)
+		os.Exit(1)
+	}
+}
+
+func mustFindAnsibleDir() string {
+	dir, err := ansible.FindAnsibleDir()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot find ansible directory: %v\n", err)
+		os.Exit(1)
+	}
+	return dir
+}
+
+// flagValue returns the value following the last occurrence of name in args.
+func flagValue(args []string, name string) string {
+	var val string
+	for i, arg := range args {
+		if arg == name && i+1 < len(args) {
+			val = args[i+1]
+		}
+	}
+	return val
+}
+
+// verbose is set by --verbose: log lines also go to stderr.
+var verbose bool
+
+// parseGlobalFlags applies flags that are valid for every command and removes
+// them from os.Args so subcommands don't see them. Arguments after "--" are
+// left alone: they belong to ansible-playbook (see flux run). --step before
+// the subcommand is rejected, since the TUI can't step.
+func parseGlobalFlags() {
+	args := []string{os.Args[0]}
+	for i := 1; i < len(os.Args); i++ {
+		arg := os.Args[i]
+		switch {
+		case arg == "--":
+			args = append(args, os.Args[i:]...)
+			i = len(os.Args)
+		case arg == "--config":
+			if i+1 >= len(os.Args) {
+				fmt.Fprintln(os.Stderr, "--config requires a path")
+				os.Exit(1)
+			}
+			config.SetFilePath(os.Args[i+1])
+			i++
+		case strings.HasPrefix(arg, "--config="):
+			config.SetFilePath(strings.TrimPrefix(arg, "--config="))
+		case arg == "--no-color":
+			os.Setenv("NO_COLOR", "1")
+		case arg == "--offline":
+			ansible.SetOffline(true)
+			updater.SetOffline()
+		case arg == "--verbose":
+			verbose = true
+		case arg == "--step" && len(args) == 1:
+			// Before any subcommand, so it would launch the TUI
+			fmt.Fprintln(os.Stderr, "Error: --step isn't supported in the TUI, which streams output and answers the become prompt itself.")
+			fmt.Fprintln(os.Stderr, "Use 'flux run --step' instead.")
+			os.Exit(1)
+		default:
+			args = append(args, arg)
+		}
+	}
+	os.Args = args
+
+	// Children (ansible-playbook, apt) inherit the setting too
+	if tui.ColorDisabled() {
+		tui.DisableColor()
+		os.Setenv("ANSIBLE_NOCOLOR", "1")
+	}
+}
+
+func cmdRun(args []string) {
+	f := parseRunFlags(args)
+	if f.sinceLast {
+		if f.safe || f.apply || f.json {
+			fatalf("Error: --since-last is a dry run; don't combine it with --safe, --apply or --json\n")
+		}
+		f.dryRun, f.changedOnly = true, true
+	}
+	tags, skipTags := f.tags.String(), f.skipTags.String()
+	if f.retryFailed {
+		tags = retryFailedTags(f)
+	}
+	if (tags != "" || skipTags != "") && !f.forceTags {
+		roles := config.AvailableRoles()
+		if dir, err := ansible.FindAnsibleDir(); err == nil {
+			roles = config.DiscoverRoles(dir)
+		}
+		for _, list := range []string{tags, skipTags} {
+			if err := config.ValidateTags(list, roles); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				fmt.Fprintln(os.Stderr, "Pass --force-tags to use tags defined inside roles.")
+				os.Exit(1)
+			}
+		}
+	}
+
+	if f.syntaxCheck {
+		cmdSyntaxCheck(f.inspectOptions(tags, skipTags))
+		return
+	}
+	if f.listTasks {
+		cmdListTasks(f.inspectOptions(tags, skipTags))
+		return
+	}
+	if f.preflight {
+		cmdPreflight(tags, skipTags)
+		return
+	}
+
+	// Remote runs don't touch this machine, so only local runs need WSL
+	remote := f.connection != "" && f.connection != "local"
+	if !remote && !platform.IsWSL() && !f.force && !f.printCommand {
+		fmt.Fprintln(os.Stderr, "Warning: not running under WSL. flux's apt and podman-WSL steps assume a WSL distro.")
+		fmt.Fprintln(os.Stderr, "Re-run with --force to continue anyway.")
+		os.Exit(1)
+	}
+
+	if f.expectHash != "" {
+		verifyAnsibleHash(f.expectHash)
+	}
+
+	// ansible --step reads its per-task prompts from stdin
+	if f.step && !isTerminal(os.Stdin) {
+		fmt.Fprintln(os.Stderr, "Error: --step needs an interactive terminal to answer the per-task prompts")
+		os.Exit(1)
+	}
+
 	cfg, err := config.LoadOrCreate()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error with config: %v\n", err)
 		os.Exit(1)
 	}
-
-	var tags string
-	var dryRun bool
-	for i, arg := range os.Args {
-		if arg == "--tags" && i+1 < len(os.Args) {
-			tags = os.Args[i+1]
+	if cfg.SafeMode && !f.dryRun && !f.apply {
+		if !f.quiet && !f.json {
+			fmt.Println("safe_mode is on: doing a dry run. Pass --apply to apply the changes.")
 		}
-		if arg == "--dry-run" {
-			dryRun = true
+		f.dryRun, f.safe = true, false
+	}
+
+	// Precedence: --set > --var-file > extra-vars file > config-derived vars
+	extraVars, err := config.LoadExtraVars(f.extraVarsFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading extra-vars file: %v\n", err)
+		os.Exit(1)
+	}
+	var fileVars map[string]interface{}
+	if f.varFile != "" {
+		fileVars, err = config.LoadVarFile(f.varFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading var file: %v\n", err)
+			os.Exit(1)
 		}
 	}
+	setVars, err := config.ParseSetVars(f.sets)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if f.checkPackages {
+		checkExtraPackages(cfg.ExtraPackages, f.yes)
+	}
+	if !remote && !f.dryRun && !f.printCommand {
+		checkRunMemory(cfg, tags, skipTags, f.force)
+	}
+	if f.postHook != "" {
+		cfg.PostRunHook = f.postHook
+	}
+	if f.noBecomePass {
+		cfg.NoBecomePass = true
+	}
+	if f.forks > 0 {
+		cfg.Forks = f.forks
+	}
+	if f.printCommand {
+		printPlaybookCommand(cfg, tags, skipTags, f, config.MergeVars(extraVars, fileVars, setVars))
+		return
+	}
+
+	ansible.SetStrictVersion(f.strict)
+	tui.RunPlaybookCLI(cfg, tui.CLIOptions{
+		RunOptions: ansible.RunOptions{
+			Inventory:     f.inventory,
+			Connection:    f.connection,
+			Limit:         f.limit,
+			RemoteUser:    f.remoteUser,
+			ExtraVars:     config.MergeVars(extraVars, fileVars, setVars),
+			Tags:          tags,
+			SkipTags:      skipTags,
+			DryRun:        f.dryRun,
+			Step:          f.step,
+			Verbosity:     f.verbosity,
+			VaultPassFile: f.vaultPassFile,
+			LogFile:       f.logFile,
+			Passthrough:   f.passthrough,
+		},
+		Safe:        f.safe,
+		Yes:         f.yes,
+		JSON:        f.json,
+		Quiet:       f.quiet,
+		ChangedOnly: f.changedOnly,
+		SinceLast:   f.sinceLast,
+	})
+}
 
-	tui.RunPlaybookCLI(cfg, tags, dryRun)
+// configUsage lists the config subcommands, the same ones cmdConfig
+// dispatches and completion offers.
+func configUsage() string {
+	return "Usage: flux config [" + strings.Join(completionConfigSubs, "|") + "]"
 }
 
-func cmdConfig(sub string) {
+func cmdConfig(sub string, args []string) {
 	switch sub {
 	case "show":
 		cfg, err := config.Load()
+		if err != nil && config.Exists() {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "No config found. Run 'flux' to create one.\n")
 			os.Exit(1)
@@ -100,12 +637,25 @@ func cmdConfig(sub string) {
 			fmt.Fprintf(os.Stderr, "Starting with defaults. Your old config will be overwritten on save.\n\n")
 			cfg = nil
 		}
-		cfg, err := config.PromptForConfig(cfg)
+		edited, err := config.PromptForConfigPartial(cfg, hasFlag(args, "--only-missing"))
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
-		if err := config.Save(cfg); err != nil {
+		if cfg != nil {
+			diffs := config.Diff(cfg, edited)
+			if len(diffs) == 0 {
+				fmt.Println("(no changes)")
+				return
+			}
+			fmt.Println()
+			printDiff(diffs)
+			if !hasFlag(args, "--yes") && !hasFlag(args, "-y") && !confirm("Save these changes?", true) {
+				fmt.Println("No changes saved.")
+				return
+			}
+		}
+		if err := config.Save(edited); err != nil {
 			fmt.Fprintf(os.Stderr, "Error saving config: %v\n", err)
 			os.Exit(1)
 		}
@@ -114,9 +664,137 @@ func cmdConfig(sub string) {
 	case "path":
 		fmt.Println(config.FilePath())
 
+	case "restore":
+		if err := config.RestoreBackup(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Restored config from %s\n", config.BackupPath())
+
+	case "init":
+		cmdConfigInit(args)
+
+	case "diff":
+		cmdConfigDiff(args)
+
+	case "use":
+		if len(args) == 0 {
+			listProfiles()
+			return
+		}
+		cmdUseProfile(args[0])
+
+	case "new-options":
+		cmdConfigNewOptions(hasFlag(args, "--quiet"))
+
+	case "set":
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, "Usage: flux config set <key> <value>  (lists are comma-separated)")
+			os.Exit(1)
+		}
+		setConfigValues([]string{args[0] + "=" + args[1]})
+		fmt.Printf("%s set.\n", args[0])
+
+	case "get":
+		if len(args) != 1 {
+			fmt.Fprintln(os.Stderr, "Usage: flux config get <key>")
+			os.Exit(1)
+		}
+		cfg, err := config.Load()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "No config found. Run 'flux' to create one.\n")
+			os.Exit(1)
+		}
+		val, err := cfg.GetField(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(val)
+
+	case "set-many":
+		if len(args) == 0 {
+			fmt.Fprintln(os.Stderr, "Usage: flux config set-many key=value [key=value ...]")
+			os.Exit(1)
+		}
+		setConfigValues(args)
+		fmt.Printf("Updated %d value(s).\n", len(args))
+
+	case "export-vars":
+		cmdConfigExportVars(args)
+
+	case "export":
+		cmdConfigExport(args)
+
+	case "import":
+		cmdConfigImport(args)
+
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown config command: %s\n", sub)
-		fmt.Println("Usage: flux config [show|edit|path]")
+		fmt.Println(configUsage())
+		os.Exit(1)
+	}
+}
+
+// listProfiles prints the available profiles, marking the active one.
+func listProfiles() {
+	names, err := config.ListProfiles()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	active := config.ActiveProfile()
+	for _, name := range append([]string{config.DefaultProfile}, names...) {
+		marker := "  "
+		if name == active {
+			marker = "* "
+		}
+		fmt.Println(marker + name)
+	}
+}
+
+// cmdUseProfile activates a profile. A profile that doesn't exist yet is
+// created from the current config so it can then be edited independently.
+func cmdUseProfile(name string) {
+	if name != config.DefaultProfile {
+		if _, err := os.Stat(config.ProfilePath(name)); os.IsNotExist(err) {
+			if err := config.SaveProfile(name, loadConfigForUpdate()); err != nil {
+				fmt.Fprintf(os.Stderr, "Error creating profile: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Created profile %s from the current config.\n", name)
+		}
+	}
+	if err := config.UseProfile(name); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Active profile: %s (%s)\n", name, config.FilePath())
+}
+
+// setConfigValues applies key=value pairs to the config, validates the
+// result and saves it, exiting with an error instead. A bad pair leaves the
+// file untouched.
+func setConfigValues(pairs []string) {
+	if err := config.SetValues(pairs); err != nil {
+		for _, line := range strings.Split(err.Error(), "\n") {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", line)
+		}
+		fmt.Fprintln(os.Stderr, "No changes saved.")
+		os.Exit(1)
+	}
+}
+
+// loadConfigForUpdate returns the saved config, or defaults if none exists yet.
+// A config file that exists but can't be parsed is a hard error.
+func loadConfigForUpdate() *config.Config {
+	cfg, err := config.Load()
+	if err == nil {
+		return cfg
+	}
+	if config.Exists() {
+		fmt.Fprintf(os.Stderr, "Error: config file is corrupt: %v\n", err)
 		os.Exit(1)
 	}
+	return config.DefaultConfig()
 }
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+func TestNoColorFlag(t *testing.T) {
+	prevArgs, prevProfile := os.Args, lipgloss.ColorProfile()
+	t.Cleanup(func() {
+		os.Args = prevArgs
+		lipgloss.SetColorProfile(prevProfile)
+	})
+	// Registered so both are restored after parseGlobalFlags sets them.
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("ANSIBLE_NOCOLOR", "")
+
+	lipgloss.SetColorProfile(termenv.TrueColor)
+	os.Args = []string{"flux", "--no-color", "status"}
+	parseGlobalFlags()
+
+	if got := strings.Join(os.Args, " "); got != "flux status" {
+		t.Errorf("os.Args = %q, want the flag removed", got)
+	}
+	if os.Getenv("NO_COLOR") == "" || os.Getenv("ANSIBLE_NOCOLOR") == "" {
+		t.Errorf("NO_COLOR=%q ANSIBLE_NOCOLOR=%q, want both set", os.Getenv("NO_COLOR"), os.Getenv("ANSIBLE_NOCOLOR"))
+	}
+	style := lipgloss.NewStyle().Foreground(lipgloss.Color("#EF4444")).Bold(true)
+	if out := style.Render("text"); strings.Contains(out, "\x1b") {
+		t.Errorf("style rendered %q after --no-color, want no ANSI codes", out)
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	prev := os.Stdin
+	t.Cleanup(func() { os.Stdin = prev })
+
+	tests := []struct {
+		input string
+		def   bool
+		want  bool
+	}{
+		{"\n", false, false},
+		{"\n", true, true},
+		{"y\n", false, true},
+		{"YES\n", false, true},
+		{" on \n", false, true},
+		{"n\n", true, false},
+		{"off\n", true, false},
+		{"maybe\n", true, false},
+		{"", true, false}, // EOF
+	}
+	for _, tt := range tests {
+		f, err := os.CreateTemp(t.TempDir(), "stdin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.WriteString(tt.input)
+		f.Seek(0, 0)
+		os.Stdin = f
+		if got := confirm("Continue?", tt.def); got != tt.want {
+			t.Errorf("confirm(%q, default %v) = %v, want %v", tt.input, tt.def, got, tt.want)
+		}
+		f.Close()
+	}
+}
+
+// dispatched returns the string cases of the first switch in function fn
+// of main.go, leaving out flags such as --version.
+func dispatched(t *testing.T, fn string) []string {
+	t.Helper()
+	file, err := parser.ParseFile(token.NewFileSet(), "main.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cases []string
+	for _, decl := range file.Decls {
+		f, ok := decl.(*ast.FuncDecl)
+		if !ok || f.Name.Name != fn {
+			continue
+		}
+		for _, stmt := range f.Body.List {
+			sw, ok := stmt.(*ast.SwitchStmt)
+			if !ok {
+				continue
+			}
+			for _, c := range sw.Body.List {
+				for _, e := range c.(*ast.CaseClause).List {
+					if lit, ok := e.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+						if name, _ := strconv.Unquote(lit.Value); !strings.HasPrefix(name, "-") {
+							cases = append(cases, name)
+						}
+					}
+				}
+			}
+			return cases
+		}
+	}
+	t.Fatalf("no switch in %s", fn)
+	return nil
+}
+
+// TestUsageMatchesDispatcher keeps `flux help` and completion (and so the
+// config usage line) in step with the commands main and cmdConfig handle.
+func TestUsageMatchesDispatcher(t *testing.T) {
+	for _, tt := range []struct {
+		fn, prefix string
+		listed     []string
+	}{
+		{"main", "flux ", completionCommands},
+		{"cmdConfig", "flux config ", completionConfigSubs},
+	} {
+		cmds := dispatched(t, tt.fn)
+		for _, cmd := range tt.listed {
+			if !slices.Contains(cmds, cmd) {
+				t.Errorf("completion offers %s%s, which %s doesn't handle", tt.prefix, cmd, tt.fn)
+			}
+		}
+		for _, cmd := range cmds {
+			if !slices.Contains(tt.listed, cmd) {
+				t.Errorf("%s%s isn't offered by completion", tt.prefix, cmd)
+			}
+			if !strings.Contains(usage, "\n  "+tt.prefix+cmd+" ") && !strings.Contains(usage, "\n  "+tt.prefix+cmd+"\n") {
+				t.Errorf("flux help doesn't describe %s%s", tt.prefix, cmd)
+			}
+		}
+	}
+}
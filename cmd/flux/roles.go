@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/jaydubyaeey/flux/internal/ansible"
+	"github.com/jaydubyaeey/flux/internal/config"
+)
+
+func cmdRoles(args []string) {
+	if len(args) == 0 || args[0] != "list" {
+		fmt.Fprintln(os.Stderr, "Usage: flux roles list [--json]")
+		os.Exit(1)
+	}
+
+	var roles []string
+	builtin := false
+	if dir, err := ansible.FindAnsibleDir(); err == nil {
+		roles = config.DiscoverRoles(dir)
+	} else {
+		roles = config.AvailableRoles()
+		builtin = true
+	}
+
+	if hasFlag(args[1:], "--json") {
+		out, _ := json.Marshal(roles)
+		fmt.Println(string(out))
+		return
+	}
+
+	if builtin {
+		fmt.Fprintln(os.Stderr, "Note: ansible directory not found; showing the built-in default roles.")
+	}
+	for _, r := range roles {
+		fmt.Println(r)
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// runFlagsHelp documents the `flux run` flags; it is part of usage and is
+// also printed by `flux run -h`.
+const runFlagsHelp = `Run flags:
+  --dry-run             Run Ansible in check mode (no changes applied);
+                        exits 2 if anything would change
+  --safe                Dry-run first and only apply if the check succeeds
+  --apply               Apply even though safe_mode is set (otherwise a dry run)
+  --since-last          Dry run, then list the roles that drifted since the last
+                        run; exits 2 if any did (implies --changed-only)
+  -t, --tags <t>        Comma-separated list of role tags to run (repeatable)
+  --skip-tags <t>       Comma-separated list of role tags to leave out (repeatable)
+  --force-tags          Allow --tags that aren't role names
+  --retry-failed        Run only the roles the last failed apply left undone
+  --extra-vars-file <p> Extra vars merged over config values
+                        (default ~/.config/flux/extra-vars.yaml, if present)
+  --var-file <path>     YAML/JSON file of extra vars (overrides the above)
+  --set <key=value>     Set an extra var (repeatable, overrides --var-file)
+  --log-file <path>     Also write all Ansible output to this file
+  -v, -vv, -vvv         Increase Ansible verbosity (repeatable)
+  --inventory <path>    Use this inventory instead of ansible/inventory.ini
+  --connection <type>   Ansible connection (default local; ssh needs --inventory)
+  --limit <hosts>       Only run against these inventory hosts
+  --user <name>         Remote user for ssh connections
+  --forks <n>           Hosts Ansible works on in parallel (overrides forks;
+                        only matters for multi-host runs)
+  --vault-password-file <p>
+                        Decrypt ansible-vault vars with this password file
+  --syntax-check        Check playbook syntax and exit without applying
+  --list-tasks          List the tasks that would run and exit
+  --preflight           Check free disk space, memory and download hosts, then exit
+  --print-command       Print the ansible-playbook command instead of running it
+  --force               Run even when not under WSL or short of memory
+  --expect-hash <sha>   Abort unless the ansible dir has this hash (see flux doctor)
+  -y, --yes             Apply without asking for confirmation
+  --json                Print a JSON result to stdout; progress goes to stderr
+  --check-packages      Warn about extra_packages apt can't find before running
+  --strict              Fail if ansible-playbook is older than the supported minimum
+  --changed-only        Hide the output of tasks that changed nothing
+                        (most useful with --dry-run)
+  --step                Confirm each task before it runs (interactive; CLI only)
+  --post-hook <cmd>     Shell command to run after a successful apply
+                        (overrides post_run_hook; config values are in FLUX_* vars)
+  --no-become-pass      Don't ask for the sudo password (passwordless sudo;
+                        same as no_become_pass in the config)
+  -q, --quiet           Only print warnings and errors besides Ansible's output
+
+Flags take their value either as --flag value or --flag=value. Anything
+after a -- separator is passed to ansible-playbook unchanged, e.g.
+  flux run --tags base -- --start-at-task="Install Go" --flush-cache
+`
+
+// runFlags holds the parsed `flux run` flags.
+type runFlags struct {
+	dryRun        bool
+	safe          bool
+	apply         bool
+	sinceLast     bool
+	tags          listValue
+	skipTags      listValue
+	forceTags     bool
+	retryFailed   bool
+	extraVarsFile string
+	varFile       string
+	sets          multiValue
+	logFile       string
+	verbosity     int
+	inventory     string
+	connection    string
+	limit         string
+	remoteUser    string
+	forks         int
+	vaultPassFile string
+	syntaxCheck   bool
+	listTasks     bool
+	preflight     bool
+	printCommand  bool
+	force         bool
+	expectHash    string
+	yes           bool
+	json          bool
+	checkPackages bool
+	strict        bool
+	changedOnly   bool
+	step          bool
+	postHook      string
+	noBecomePass  bool
+	quiet         bool
+
+	// passthrough is everything after "--", for ansible-playbook itself
+	passthrough []string
+}
+
+// parseRunFlags parses the arguments after `flux run`. Unknown flags and
+// missing values print an error and the run help, then exit.
+func parseRunFlags(args []string) *runFlags {
+	f := &runFlags{}
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), "Usage: flux run [flags]\n\n"+runFlagsHelp)
+	}
+
+	fs.BoolVar(&f.dryRun, "dry-run", false, "")
+	fs.BoolVar(&f.safe, "safe", false, "")
+	fs.BoolVar(&f.apply, "apply", false, "")
+	fs.BoolVar(&f.sinceLast, "since-last", false, "")
+	fs.Var(&f.tags, "tags", "")
+	fs.Var(&f.tags, "t", "")
+	fs.Var(&f.skipTags, "skip-tags", "")
+	fs.BoolVar(&f.forceTags, "force-tags", false, "")
+	fs.BoolVar(&f.retryFailed, "retry-failed", false, "")
+	fs.StringVar(&f.extraVarsFile, "extra-vars-file", "", "")
+	fs.StringVar(&f.varFile, "var-file", "", "")
+	fs.Var(&f.sets, "set", "")
+	fs.StringVar(&f.logFile, "log-file", "", "")
+	for n := 1; n <= 4; n++ {
+		fs.Var(countValue{&f.verbosity, n}, strings.Repeat("v", n), "")
+	}
+	fs.StringVar(&f.inventory, "inventory", "", "")
+	fs.StringVar(&f.connection, "connection", "", "")
+	fs.StringVar(&f.limit, "limit", "", "")
+	fs.StringVar(&f.remoteUser, "user", "", "")
+	fs.Var(positiveValue{&f.forks}, "forks", "")
+	fs.StringVar(&f.vaultPassFile, "vault-password-file", "", "")
+	fs.BoolVar(&f.syntaxCheck, "syntax-check", false, "")
+	fs.BoolVar(&f.listTasks, "list-tasks", false, "")
+	fs.BoolVar(&f.preflight, "preflight", false, "")
+	fs.BoolVar(&f.printCommand, "print-command", false, "")
+	fs.BoolVar(&f.force, "force", false, "")
+	fs.StringVar(&f.expectHash, "expect-hash", "", "")
+	fs.BoolVar(&f.yes, "yes", false, "")
+	fs.BoolVar(&f.yes, "y", false, "")
+	fs.BoolVar(&f.json, "json", false, "")
+	fs.BoolVar(&f.checkPackages, "check-packages", false, "")
+	fs.BoolVar(&f.strict, "strict", false, "")
+	fs.BoolVar(&f.changedOnly, "changed-only", false, "")
+	fs.BoolVar(&f.step, "step", false, "")
+	fs.StringVar(&f.postHook, "post-hook", "", "")
+	fs.BoolVar(&f.noBecomePass, "no-become-pass", false, "")
+	fs.BoolVar(&f.quiet, "quiet", false, "")
+	fs.BoolVar(&f.quiet, "q", false, "")
+
+	for i, arg := range args {
+		if arg == "--" {
+			args, f.passthrough = args[:i], args[i+1:]
+			break
+		}
+	}
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "Unexpected argument %q (see flux run -h)\n", fs.Arg(0))
+		os.Exit(2)
+	}
+	return f
+}
+
+// listValue is a repeatable flag of comma-separated values, so
+// "--tags a,b -t c" yields a, b, c. Duplicates are dropped.
+type listValue []string
+
+func (l *listValue) String() string { return strings.Join(*l, ",") }
+
+func (l *listValue) Set(s string) error {
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		dup := false
+		for _, have := range *l {
+			dup = dup || have == v
+		}
+		if !dup {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+// multiValue is a repeatable flag that keeps each value as given.
+type multiValue []string
+
+func (m *multiValue) String() string { return strings.Join(*m, " ") }
+
+func (m *multiValue) Set(s string) error {
+	*m = append(*m, s)
+	return nil
+}
+
+// positiveValue is an int flag that must be at least 1.
+type positiveValue struct{ n *int }
+
+func (p positiveValue) String() string { return "" }
+
+func (p positiveValue) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return fmt.Errorf("%q is not a positive integer", s)
+	}
+	*p.n = n
+	return nil
+}
+
+// countValue adds n to *count each time its flag is given, so -v -vv
+// counts as three.
+type countValue struct {
+	count *int
+	n     int
+}
+
+func (c countValue) IsBoolFlag() bool { return true }
+
+func (c countValue) String() string { return "" }
+
+func (c countValue) Set(s string) error {
+	on, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	if on {
+		*c.count += c.n
+	}
+	return nil
+}
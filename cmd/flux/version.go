@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/jaydubyaeey/flux/internal/ansible"
+	"github.com/jaydubyaeey/flux/internal/updater"
+)
+
+const version = "0.1.0"
+
+// Build metadata, set at build time with
+//
+//	go build -ldflags "-X main.commit=<sha> -X main.buildDate=<RFC 3339>"
+//
+// as install.sh and flux update do. Builds without them fall back to the
+// VCS information the Go toolchain embeds.
+var (
+	commit    string
+	buildDate string
+)
+
+// buildInfo returns the commit and build date, "unknown" when neither the
+// linker flags nor the embedded VCS information provide them.
+func buildInfo() (rev, date string) {
+	rev, date = commit, buildDate
+	if info, ok := debug.ReadBuildInfo(); ok && rev == "" {
+		dirty := false
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				rev = s.Value
+				if len(rev) > 12 {
+					rev = rev[:12]
+				}
+			case "vcs.time":
+				if date == "" {
+					date = s.Value
+				}
+			case "vcs.modified":
+				dirty = s.Value == "true"
+			}
+		}
+		if rev != "" && dirty {
+			rev += "-dirty"
+		}
+	}
+	if rev == "" {
+		rev = "unknown"
+	}
+	if date == "" {
+		date = "unknown"
+	}
+	return rev, date
+}
+
+// cmdVersion prints the flux and ansible versions. --full adds the build
+// metadata and install location, for bug reports.
+func cmdVersion(args []string) {
+	fmt.Printf("flux %s\n", version)
+	ansibleVersion := "not installed"
+	if v, err := ansible.Version(); err == nil {
+		ansibleVersion = v
+	}
+	fmt.Printf("ansible: %s\n", ansibleVersion)
+	if !hasFlag(args, "--full") {
+		return
+	}
+
+	rev, date := buildInfo()
+	installDir := "(not found)"
+	if dir, method, err := updater.DetectInstall(); err == nil {
+		installDir = fmt.Sprintf("%s (%s)", dir, method)
+	}
+	fmt.Printf("commit: %s\n", rev)
+	fmt.Printf("built: %s\n", date)
+	fmt.Printf("go: %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	fmt.Printf("install dir: %s\n", installDir)
+	fmt.Printf("binary: %s\n", updater.BinPath())
+}
@@ -2,9 +2,9 @@
 set -euo pipefail
 
 REPO="https://github.com/jaydubyaeey/flux.git"
-INSTALL_DIR="$HOME/.local/share/flux"
-BIN_DIR="$HOME/.local/bin"
-BIN="$BIN_DIR/flux"
+INSTALL_DIR="${FLUX_INSTALL_DIR:-$HOME/.local/share/flux}"
+BIN="${FLUX_BIN_PATH:-$HOME/.local/bin/flux}"
+BIN_DIR="$(dirname "$BIN")"
 GO_INSTALL_DIR="/usr/local/go"
 GO_BIN="$GO_INSTALL_DIR/bin/go"
 GO_FALLBACK_VERSION="1.23.4"
@@ -13,7 +13,7 @@ echo ""
 echo "  ⚡ flux — WSL bootstrap"
 echo ""
 
-# Ensure ~/.local/bin exists
+# Ensure the binary directory exists
 mkdir -p "$BIN_DIR"
 
 # Install git and curl if missing
@@ -99,7 +99,8 @@ fi
 
 # Build
 echo "→ Building..."
-"$GO_BIN" build -o "$BIN" ./cmd/flux
+LDFLAGS="-X main.commit=$(git rev-parse --short=12 HEAD) -X main.buildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+"$GO_BIN" build -ldflags "$LDFLAGS" -o "$BIN" ./cmd/flux
 
 # --- Ensure ~/.local/bin is on PATH persistently ---
 if ! echo "$PATH" | grep -q "$BIN_DIR"; then
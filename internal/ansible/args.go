@@ -0,0 +1,224 @@
+package ansible
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// RunOptions describes one ansible-playbook run.
+type RunOptions struct {
+	AnsibleDir string // directory holding playbook.yml
+
+	// Targets; see targetArgs. Inventory defaults to the bundled
+	// inventory.ini.
+	Inventory  string
+	Connection string
+	Limit      string
+	RemoteUser string
+
+	ExtraVars map[string]interface{}
+	Tags      string // comma-separated --tags, empty for all roles
+	SkipTags  string // comma-separated --skip-tags
+	DryRun    bool   // --check --diff
+	Step      bool   // --step; needs an interactive terminal
+	Verbosity int    // number of -v flags
+	Forks     int    // --forks when > 0, else ansible's default
+
+	VaultPassFile string // passed as --vault-password-file when set
+
+	// BecomePass, when not running as root, is written to a temp file
+	// passed as --become-password-file. Empty means --ask-become-pass.
+	BecomePass string
+	// NoBecomePass passes neither, for passwordless sudo.
+	NoBecomePass bool
+
+	LogFile string // also write all output here when set
+
+	// Capture, when set, also receives ansible's stdout, without colour.
+	// ansible then writes to a pipe instead of the terminal, which --step
+	// prompts need, so leave it nil for step runs.
+	Capture io.Writer
+
+	// Passthrough is appended verbatim after flux's own arguments, for
+	// ansible-playbook flags flux doesn't model (flux run ... -- <flags>).
+	Passthrough []string
+}
+
+// buildPlaybookArgs assembles the ansible-playbook arguments for opts. It is
+// shared by every runner so they can't drift apart. Vault password file
+// warnings, and warnings about passthrough flags flux already sets, go to
+// warn. cleanup removes the temp become password file, if one was written,
+// and must be called once ansible has exited.
+func buildPlaybookArgs(opts RunOptions, warn OutputFunc) (args []string, cleanup func(), err error) {
+	args, cleanup, err = fluxPlaybookArgs(opts, warn)
+	if err != nil {
+		return nil, cleanup, err
+	}
+	// Still forwarded: the user may know better, e.g. a second --tags
+	for _, flag := range passthroughConflicts(args, opts.Passthrough) {
+		warn(fmt.Sprintf("Warning: %s after -- repeats a flag flux already passes; ansible-playbook gets both", flag))
+	}
+	return append(args, opts.Passthrough...), cleanup, nil
+}
+
+// fluxPlaybookArgs is buildPlaybookArgs without the passthrough flags.
+func fluxPlaybookArgs(opts RunOptions, warn OutputFunc) (args []string, cleanup func(), err error) {
+	cleanup = func() {}
+	playbook := filepath.Join(opts.AnsibleDir, "playbook.yml")
+
+	if _, err := os.Stat(playbook); err != nil {
+		return nil, cleanup, fmt.Errorf("playbook not found: %s", playbook)
+	}
+
+	target, err := targetArgs(opts.AnsibleDir, opts.Inventory, opts.Connection, opts.Limit, opts.RemoteUser)
+	if err != nil {
+		return nil, cleanup, err
+	}
+	args = append([]string{playbook}, target...)
+
+	if extraVars := withOffline(withTargetHosts(opts.ExtraVars, opts.Connection)); len(extraVars) > 0 {
+		varsJSON, err := json.Marshal(extraVars)
+		if err != nil {
+			return nil, cleanup, fmt.Errorf("failed to marshal extra vars: %w", err)
+		}
+		args = append(args, "--extra-vars", string(varsJSON))
+	}
+
+	if opts.Tags != "" {
+		args = append(args, "--tags", opts.Tags)
+	}
+
+	if opts.SkipTags != "" {
+		args = append(args, "--skip-tags", opts.SkipTags)
+	}
+
+	if opts.DryRun {
+		args = append(args, "--check", "--diff")
+	}
+
+	if opts.Step {
+		args = append(args, "--step")
+	}
+
+	if opts.Verbosity > 0 {
+		args = append(args, "-"+strings.Repeat("v", opts.Verbosity))
+	}
+
+	if opts.Forks > 0 {
+		args = append(args, "--forks", strconv.Itoa(opts.Forks))
+	}
+
+	if opts.VaultPassFile != "" {
+		warning, err := checkVaultPasswordFile(opts.VaultPassFile)
+		if err != nil {
+			return nil, cleanup, err
+		}
+		if warning != "" {
+			warn(warning)
+		}
+		args = append(args, "--vault-password-file", opts.VaultPassFile)
+	}
+
+	if os.Getuid() == 0 || opts.NoBecomePass {
+		return args, cleanup, nil
+	}
+	if opts.BecomePass == "" {
+		return append(args, "--ask-become-pass"), cleanup, nil
+	}
+
+	// Hand the password over in an owner-only temp file
+	tmpFile, err := os.CreateTemp("", "flux-become-*")
+	if err != nil {
+		return nil, cleanup, fmt.Errorf("failed to create temp password file: %w", err)
+	}
+	cleanup = func() { os.Remove(tmpFile.Name()) }
+
+	if err := os.Chmod(tmpFile.Name(), 0600); err != nil {
+		tmpFile.Close()
+		cleanup()
+		return nil, func() {}, fmt.Errorf("failed to chmod temp password file: %w", err)
+	}
+	if _, err := tmpFile.WriteString(opts.BecomePass); err != nil {
+		tmpFile.Close()
+		cleanup()
+		return nil, func() {}, fmt.Errorf("failed to write temp password file: %w", err)
+	}
+	tmpFile.Close()
+
+	return append(args, "--become-password-file", tmpFile.Name()), cleanup, nil
+}
+
+// shortFlags maps ansible-playbook's short flags to their long names, so a
+// passthrough -t is seen to clash with flux's --tags.
+var shortFlags = map[string]string{
+	"-i": "--inventory", "-c": "--connection", "-l": "--limit", "-u": "--user",
+	"-e": "--extra-vars", "-t": "--tags", "-C": "--check", "-D": "--diff",
+	"-f": "--forks", "-K": "--ask-become-pass",
+}
+
+// flagName returns the long name of a command-line flag, without any
+// "=value", or "" if arg isn't a flag.
+func flagName(arg string) string {
+	if !strings.HasPrefix(arg, "-") || arg == "-" {
+		return ""
+	}
+	name, _, _ := strings.Cut(arg, "=")
+	if long, ok := shortFlags[name]; ok {
+		return long
+	}
+	return name
+}
+
+// passthroughConflicts returns the flags in passthrough, as typed, that
+// args, flux's own ansible-playbook arguments, already contain. --extra-vars
+// is left out since ansible merges repeated ones.
+func passthroughConflicts(args, passthrough []string) []string {
+	have := map[string]bool{}
+	for _, a := range args {
+		have[flagName(a)] = true
+	}
+	var out []string
+	for _, a := range passthrough {
+		if name := flagName(a); name != "" && name != "--extra-vars" && have[name] {
+			typed, _, _ := strings.Cut(a, "=")
+			out = append(out, typed)
+		}
+	}
+	return out
+}
+
+// stderrWarn prints a warning for the non-streaming runners.
+func stderrWarn(warning string) {
+	fmt.Fprintln(os.Stderr, warning)
+}
+
+// PlaybookCommand returns the arguments RunPlaybook passes to
+// ansible-playbook for opts, including --ask-become-pass when not running as
+// root. opts.BecomePass is ignored. `flux run --print-command` prints them.
+func PlaybookCommand(opts RunOptions) ([]string, error) {
+	opts.BecomePass = ""
+	args, _, err := buildPlaybookArgs(opts, stderrWarn)
+	return args, err
+}
+
+// shellSafe matches arguments that need no quoting in a POSIX shell.
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// ShellCommand renders ansible-playbook with args as a command line that can
+// be pasted into a POSIX shell, single-quoting arguments where needed.
+func ShellCommand(args []string) string {
+	parts := []string{"ansible-playbook"}
+	for _, a := range args {
+		if !shellSafe.MatchString(a) {
+			a = "'" + strings.ReplaceAll(a, "'", `'\''`) + "'"
+		}
+		parts = append(parts, a)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,292 @@
+package ansible
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jaydubyaeey/flux/internal/config"
+)
+
+// testAnsibleDir returns a temp dir holding an empty playbook.yml and
+// inventory.ini, enough for buildPlaybookArgs.
+func testAnsibleDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range []string{"playbook.yml", "inventory.ini"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// flagValue returns the argument following flag in args, and whether flag
+// was there at all.
+func flagValue(args []string, flag string) (string, bool) {
+	for i, a := range args {
+		if a == flag {
+			if i+1 < len(args) {
+				return args[i+1], true
+			}
+			return "", true
+		}
+	}
+	return "", false
+}
+
+func TestDryRunSkipsCheckModeExcludedRoles(t *testing.T) {
+	dir := testAnsibleDir(t)
+	cfg := config.DefaultConfig()
+	cfg.CheckModeExcludedRoles = []string{"podman", "k9s"}
+
+	tests := []struct {
+		name     string
+		skipTags string
+		dryRun   bool
+		want     string // --skip-tags value, "" for none
+	}{
+		{"dry run skips excluded roles", "", true, "podman,k9s"},
+		{"dry run keeps the user's skip-tags", "dotnet", true, "dotnet,podman,k9s"},
+		{"apply runs excluded roles", "", false, ""},
+		{"apply keeps only the user's skip-tags", "dotnet", false, "dotnet"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, cleanup, err := buildPlaybookArgs(RunOptions{
+				AnsibleDir:   dir,
+				SkipTags:     cfg.RunSkipTags(tt.skipTags, tt.dryRun),
+				DryRun:       tt.dryRun,
+				NoBecomePass: true,
+			}, func(string) {})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer cleanup()
+
+			got, ok := flagValue(args, "--skip-tags")
+			if tt.want == "" && ok {
+				t.Errorf("--skip-tags %q passed, want none in %q", got, args)
+			} else if got != tt.want {
+				t.Errorf("--skip-tags = %q, want %q", got, tt.want)
+			}
+			if _, check := flagValue(args, "--check"); check != tt.dryRun {
+				t.Errorf("--check present = %v, want %v in %s", check, tt.dryRun, strings.Join(args, " "))
+			}
+		})
+	}
+}
+
+// hasSeq reports whether want appears in args as consecutive elements.
+func hasSeq(args, want []string) bool {
+	for i := 0; i+len(want) <= len(args); i++ {
+		if strings.Join(args[i:i+len(want)], "\x00") == strings.Join(want, "\x00") {
+			return true
+		}
+	}
+	return false
+}
+
+func TestPlaybookArgsFlags(t *testing.T) {
+	dir := testAnsibleDir(t)
+	tests := []struct {
+		name   string
+		opts   RunOptions
+		want   []string // must appear, in this order
+		absent []string // flags that must not appear
+	}{
+		{"defaults", RunOptions{}, nil, []string{"--tags", "--skip-tags", "--check", "--diff", "--step", "--forks", "-v"}},
+		{"tags", RunOptions{Tags: "go,node"}, []string{"--tags", "go,node"}, []string{"--skip-tags"}},
+		{"skip-tags", RunOptions{SkipTags: "dotnet"}, []string{"--skip-tags", "dotnet"}, []string{"--tags"}},
+		{"dry run", RunOptions{DryRun: true}, []string{"--check", "--diff"}, nil},
+		{"step", RunOptions{Step: true}, []string{"--step"}, nil},
+		{"verbosity 1", RunOptions{Verbosity: 1}, []string{"-v"}, []string{"-vv"}},
+		{"verbosity 3", RunOptions{Verbosity: 3}, []string{"-vvv"}, []string{"-v"}},
+		{"forks", RunOptions{Forks: 12}, []string{"--forks", "12"}, nil},
+		{"zero forks uses ansible's default", RunOptions{Forks: 0}, nil, []string{"--forks"}},
+		{"extra vars", RunOptions{ExtraVars: map[string]interface{}{"username": "jay"}}, []string{"--extra-vars", `{"username":"jay"}`}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.AnsibleDir = dir
+			tt.opts.NoBecomePass = true
+			args, cleanup, err := buildPlaybookArgs(tt.opts, func(w string) { t.Errorf("unexpected warning: %s", w) })
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer cleanup()
+
+			if args[0] != filepath.Join(dir, "playbook.yml") {
+				t.Errorf("args[0] = %q, want the playbook", args[0])
+			}
+			if tt.want != nil && !hasSeq(args, tt.want) {
+				t.Errorf("args %q don't contain %q", args, tt.want)
+			}
+			for _, flag := range tt.absent {
+				if _, ok := flagValue(args, flag); ok {
+					t.Errorf("args %q contain %s", args, flag)
+				}
+			}
+		})
+	}
+}
+
+func TestPlaybookArgsMissingPlaybook(t *testing.T) {
+	if _, _, err := buildPlaybookArgs(RunOptions{AnsibleDir: t.TempDir()}, func(string) {}); err == nil {
+		t.Error("buildPlaybookArgs succeeded without a playbook.yml")
+	}
+}
+
+func TestPlaybookArgsVault(t *testing.T) {
+	dir := testAnsibleDir(t)
+	private := filepath.Join(t.TempDir(), "vault-pass")
+	if err := os.WriteFile(private, []byte("secret"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	shared := filepath.Join(t.TempDir(), "vault-pass")
+	if err := os.WriteFile(shared, []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		file     string
+		wantErr  bool
+		wantWarn bool
+	}{
+		{"owner-only file", private, false, false},
+		{"readable by others warns", shared, false, true},
+		{"missing file", filepath.Join(t.TempDir(), "nope"), true, false},
+		{"directory", t.TempDir(), true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var warnings []string
+			args, cleanup, err := buildPlaybookArgs(RunOptions{
+				AnsibleDir:    dir,
+				VaultPassFile: tt.file,
+				NoBecomePass:  true,
+			}, func(w string) { warnings = append(warnings, w) })
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("buildPlaybookArgs succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer cleanup()
+
+			if got, _ := flagValue(args, "--vault-password-file"); got != tt.file {
+				t.Errorf("--vault-password-file = %q, want %q", got, tt.file)
+			}
+			if warned := len(warnings) > 0; warned != tt.wantWarn {
+				t.Errorf("warnings = %q, want warning: %v", warnings, tt.wantWarn)
+			}
+		})
+	}
+}
+
+func TestPlaybookArgsBecome(t *testing.T) {
+	dir := testAnsibleDir(t)
+	root := os.Getuid() == 0
+
+	t.Run("no become pass", func(t *testing.T) {
+		args, cleanup, err := buildPlaybookArgs(RunOptions{AnsibleDir: dir, NoBecomePass: true, BecomePass: "pw"}, func(string) {})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer cleanup()
+		for _, flag := range []string{"--ask-become-pass", "--become-password-file"} {
+			if _, ok := flagValue(args, flag); ok {
+				t.Errorf("args contain %s with NoBecomePass", flag)
+			}
+		}
+	})
+
+	t.Run("ask", func(t *testing.T) {
+		args, cleanup, err := buildPlaybookArgs(RunOptions{AnsibleDir: dir}, func(string) {})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer cleanup()
+		if _, ok := flagValue(args, "--ask-become-pass"); ok == root {
+			t.Errorf("--ask-become-pass present = %v as uid %d", ok, os.Getuid())
+		}
+	})
+
+	t.Run("password file", func(t *testing.T) {
+		if root {
+			t.Skip("root never needs a become password")
+		}
+		args, cleanup, err := buildPlaybookArgs(RunOptions{AnsibleDir: dir, BecomePass: "pw"}, func(string) {})
+		if err != nil {
+			t.Fatal(err)
+		}
+		file, ok := flagValue(args, "--become-password-file")
+		if !ok {
+			cleanup()
+			t.Fatalf("args %q have no --become-password-file", args)
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if perm := info.Mode().Perm(); perm != 0600 {
+			t.Errorf("password file mode = %04o, want 0600", perm)
+		}
+		if data, _ := os.ReadFile(file); string(data) != "pw" {
+			t.Errorf("password file holds %q", data)
+		}
+		cleanup()
+		if _, err := os.Stat(file); !os.IsNotExist(err) {
+			t.Errorf("password file still exists after cleanup")
+		}
+	})
+}
+
+func TestPlaybookArgsPassthrough(t *testing.T) {
+	dir := testAnsibleDir(t)
+	tests := []struct {
+		name        string
+		opts        RunOptions
+		passthrough []string
+		conflicts   []string
+	}{
+		{"new flags", RunOptions{}, []string{"--start-at-task", "Install go"}, nil},
+		{"repeated --tags", RunOptions{Tags: "go"}, []string{"--tags", "node"}, []string{"--tags"}},
+		{"short flag for a long one", RunOptions{Tags: "go"}, []string{"-t", "node"}, []string{"-t"}},
+		{"flag=value form", RunOptions{Forks: 4}, []string{"--forks=8"}, []string{"--forks"}},
+		{"check in a dry run", RunOptions{DryRun: true}, []string{"-C"}, []string{"-C"}},
+		{"check in an apply", RunOptions{}, []string{"--check"}, nil},
+		{"extra vars merge", RunOptions{ExtraVars: map[string]interface{}{"a": 1}}, []string{"-e", "b=2"}, nil},
+		{"values aren't flags", RunOptions{Tags: "go"}, []string{"--start-at-task", "go"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.AnsibleDir = dir
+			tt.opts.NoBecomePass = true
+			tt.opts.Passthrough = tt.passthrough
+			var warnings []string
+			args, cleanup, err := buildPlaybookArgs(tt.opts, func(w string) { warnings = append(warnings, w) })
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer cleanup()
+
+			if tail := args[len(args)-len(tt.passthrough):]; !hasSeq(tail, tt.passthrough) {
+				t.Errorf("args end in %q, want the passthrough %q verbatim", tail, tt.passthrough)
+			}
+			if len(warnings) != len(tt.conflicts) {
+				t.Fatalf("warnings = %q, want one for each of %q", warnings, tt.conflicts)
+			}
+			for i, flag := range tt.conflicts {
+				if !strings.Contains(warnings[i], flag+" after --") {
+					t.Errorf("warning %q doesn't name %s", warnings[i], flag)
+				}
+			}
+		})
+	}
+}
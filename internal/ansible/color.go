@@ -0,0 +1,46 @@
+package ansible
+
+import (
+	"io"
+	"os"
+	"regexp"
+)
+
+// ansiEscape matches the colour sequences ansible prints with
+// ANSIBLE_FORCE_COLOR; ansiPartial matches the start of one cut off at the
+// end of a write.
+var (
+	ansiEscape  = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+	ansiPartial = regexp.MustCompile(`\x1b(\[[0-9;]*)?$`)
+)
+
+// plainWriter writes to w with colour sequences removed. A sequence split
+// across two writes is held back until the rest arrives.
+type plainWriter struct {
+	w       io.Writer
+	pending []byte
+}
+
+func (p *plainWriter) Write(b []byte) (int, error) {
+	data := append(p.pending, b...)
+	p.pending = nil
+	if loc := ansiPartial.FindIndex(data); loc != nil {
+		p.pending = append([]byte(nil), data[loc[0]:]...)
+		data = data[:loc[0]]
+	}
+	if _, err := p.w.Write(ansiEscape.ReplaceAll(data, nil)); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// keepColor reports whether ansible should be told to print colour although
+// its output goes through a pipe: flux's own stdout is a terminal and colour
+// hasn't been turned off.
+func keepColor() bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("ANSIBLE_NOCOLOR") != "" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,44 @@
+package ansible
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestPlainWriter(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []string
+		want   string
+	}{
+		{"plain text", []string{"ok: [localhost]\n"}, "ok: [localhost]\n"},
+		{"colour removed", []string{"\x1b[0;32mok: [localhost]\x1b[0m\n"}, "ok: [localhost]\n"},
+		{"sequence split across writes", []string{"\x1b[0;3", "3mchanged\x1b", "[0m\n"}, "changed\n"},
+		{"escape at the very end", []string{"done\x1b", "[0m"}, "done"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := &plainWriter{w: &buf}
+			for _, s := range tt.writes {
+				if n, err := w.Write([]byte(s)); err != nil || n != len(s) {
+					t.Fatalf("Write(%q) = %d, %v", s, n, err)
+				}
+			}
+			if buf.String() != tt.want {
+				t.Errorf("wrote %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestTeeKeepsLoneFile(t *testing.T) {
+	if w := tee([]io.Writer{os.Stdout}); w != io.Writer(os.Stdout) {
+		t.Errorf("tee of os.Stdout alone = %T, want the *os.File so ansible keeps the terminal", w)
+	}
+	if _, ok := tee([]io.Writer{os.Stdout, &bytes.Buffer{}}).(*os.File); ok {
+		t.Error("tee of two writers returned a single file")
+	}
+}
@@ -0,0 +1,260 @@
+package ansible
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Task statuses reported in TaskEvent.Status. StatusStarted has no host: it
+// marks the task beginning, before any host has reported.
+const (
+	StatusStarted     = "started"
+	StatusOk          = "ok"
+	StatusChanged     = "changed"
+	StatusSkipped     = "skipped"
+	StatusFailed      = "failed"
+	StatusUnreachable = "unreachable"
+)
+
+// TaskEvent is the result of one task on one host.
+type TaskEvent struct {
+	Task    string
+	Host    string
+	Status  string
+	Changed bool
+}
+
+// callbackResult is the part of a host's result the events need.
+type callbackResult struct {
+	Changed     bool `json:"changed"`
+	Failed      bool `json:"failed"`
+	Skipped     bool `json:"skipped"`
+	Unreachable bool `json:"unreachable"`
+}
+
+type callbackTask struct {
+	Task struct {
+		Name string `json:"name"`
+	} `json:"task"`
+	Hosts map[string]callbackResult `json:"hosts"`
+}
+
+// callbackDoc is one JSON value written by the json callback (the whole run,
+// under "plays") or the jsonl callback (one event, named by "_event").
+type callbackDoc struct {
+	callbackTask
+	Event string `json:"_event"`
+	Plays []struct {
+		Tasks []callbackTask `json:"tasks"`
+	} `json:"plays"`
+}
+
+// ParseJSONCallback reads the output of ansible-playbook run with
+// ANSIBLE_STDOUT_CALLBACK=json or ansible.posix.jsonl and sends a TaskEvent
+// for each task result, in order, closing the channel at the end of r. The
+// json callback only writes its document when the run ends, so its events
+// all arrive at once; jsonl streams them as tasks finish. An error is
+// returned straight away if r doesn't start with a JSON object, so callers
+// can fall back to PlainEventParser. The channel must be drained.
+func ParseJSONCallback(r io.Reader) (<-chan TaskEvent, error) {
+	br := bufio.NewReader(r)
+	first, err := peekNonSpace(br)
+	if err != nil {
+		return nil, err
+	}
+	if first != '{' {
+		return nil, fmt.Errorf("not JSON callback output (starts with %q)", first)
+	}
+
+	events := make(chan TaskEvent)
+	go func() {
+		defer close(events)
+		dec := json.NewDecoder(br)
+		for {
+			var doc callbackDoc
+			if err := dec.Decode(&doc); err != nil {
+				return
+			}
+			for _, ev := range doc.events() {
+				events <- ev
+			}
+		}
+	}()
+	return events, nil
+}
+
+// peekNonSpace returns the first byte of br that isn't whitespace, leaving
+// it unread.
+func peekNonSpace(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		if !bytes.ContainsAny(b, " \t\r\n") {
+			return b[0], nil
+		}
+		br.ReadByte()
+	}
+}
+
+// events converts one callback document into TaskEvents.
+func (d callbackDoc) events() []TaskEvent {
+	if len(d.Plays) > 0 {
+		var out []TaskEvent
+		for _, p := range d.Plays {
+			for _, t := range p.Tasks {
+				out = append(out, t.events("")...)
+			}
+		}
+		return out
+	}
+	switch d.Event {
+	case "v2_playbook_on_task_start", "v2_playbook_on_handler_task_start":
+		return []TaskEvent{{Task: d.Task.Name, Status: StatusStarted}}
+	case "v2_runner_on_ok", "v2_runner_on_failed", "v2_runner_on_skipped", "v2_runner_on_unreachable":
+		return d.callbackTask.events(strings.TrimPrefix(d.Event, "v2_runner_on_"))
+	}
+	return nil
+}
+
+// events converts a task's per-host results. status, if set, is the jsonl
+// event's outcome (its names match the Status constants); otherwise it
+// comes from the result flags.
+func (t callbackTask) events(status string) []TaskEvent {
+	hosts := make([]string, 0, len(t.Hosts))
+	for h := range t.Hosts {
+		hosts = append(hosts, h)
+	}
+	sort.Strings(hosts)
+
+	out := make([]TaskEvent, 0, len(hosts))
+	for _, h := range hosts {
+		res := t.Hosts[h]
+		st := status
+		if st == "" {
+			switch {
+			case res.Unreachable:
+				st = StatusUnreachable
+			case res.Failed:
+				st = StatusFailed
+			case res.Skipped:
+				st = StatusSkipped
+			default:
+				st = StatusOk
+			}
+		}
+		if st == StatusOk && res.Changed {
+			st = StatusChanged
+		}
+		out = append(out, TaskEvent{Task: t.Task.Name, Host: h, Status: st, Changed: res.Changed})
+	}
+	return out
+}
+
+// TaskRole returns the role a task belongs to, from the "role : task" name
+// ansible gives role tasks, or "" for tasks of the play itself.
+func TaskRole(task string) string {
+	role, _, ok := strings.Cut(task, " : ")
+	if !ok {
+		return ""
+	}
+	return strings.TrimSpace(role)
+}
+
+// RetryRoles returns the roles of selected that a failed run left undone:
+// those with a failed or unreachable task, and those that never started
+// because the play stopped first. Roles whose tasks all ran are left out.
+func RetryRoles(selected []string, events []TaskEvent) []string {
+	started := map[string]bool{}
+	failed := map[string]bool{}
+	for _, ev := range events {
+		role := TaskRole(ev.Task)
+		if role == "" {
+			continue
+		}
+		started[role] = true
+		if ev.Status == StatusFailed || ev.Status == StatusUnreachable {
+			failed[role] = true
+		}
+	}
+	var out []string
+	for _, r := range selected {
+		if failed[r] || !started[r] {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// ChangedRoles returns the roles with a task that reported a change, in
+// the order they ran.
+func ChangedRoles(events []TaskEvent) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, ev := range events {
+		role := TaskRole(ev.Task)
+		if role == "" || seen[role] || !(ev.Changed || ev.Status == StatusChanged) {
+			continue
+		}
+		seen[role] = true
+		out = append(out, role)
+	}
+	return out
+}
+
+// hostResultLine matches the per-host result lines of the default callback,
+// e.g. "changed: [localhost]" or "fatal: [web1]: FAILED! => ...".
+var hostResultLine = regexp.MustCompile(`^(ok|changed|skipping|failed|fatal): \[([^\]]+)\]`)
+
+// taskHeader matches "TASK [role : name] ****" and handler headers.
+var taskHeader = regexp.MustCompile(`^(?:TASK|RUNNING HANDLER) \[(.*)\]`)
+
+// PlainEventParser derives TaskEvents from the default callback's
+// human-readable output, for runs without a JSON callback. Feed it every
+// line in order; it remembers the current task between lines. Lines that
+// are themselves jsonl events (ANSIBLE_STDOUT_CALLBACK=ansible.posix.jsonl)
+// are decoded as such.
+type PlainEventParser struct {
+	task string
+}
+
+// Line returns the events reported by one line of output, if any.
+func (p *PlainEventParser) Line(line string) []TaskEvent {
+	trimmed := strings.TrimSpace(line)
+	if strings.HasPrefix(trimmed, "{") {
+		var doc callbackDoc
+		if json.Unmarshal([]byte(trimmed), &doc) == nil {
+			return doc.events()
+		}
+	}
+	if m := taskHeader.FindStringSubmatch(trimmed); m != nil {
+		p.task = m[1]
+		return []TaskEvent{{Task: p.task, Status: StatusStarted}}
+	}
+	m := hostResultLine.FindStringSubmatch(trimmed)
+	if m == nil {
+		return nil
+	}
+	ev := TaskEvent{Task: p.task, Host: m[2]}
+	switch m[1] {
+	case "ok":
+		ev.Status = StatusOk
+	case "changed":
+		ev.Status, ev.Changed = StatusChanged, true
+	case "skipping":
+		ev.Status = StatusSkipped
+	default:
+		ev.Status = StatusFailed
+		if strings.Contains(trimmed, "UNREACHABLE!") {
+			ev.Status = StatusUnreachable
+		}
+	}
+	return []TaskEvent{ev}
+}
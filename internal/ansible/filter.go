@@ -0,0 +1,57 @@
+package ansible
+
+import "strings"
+
+// ChangedOnly wraps onOutput so the output of tasks that only reported ok or
+// skipping is dropped, while changed, failed and --diff output passes
+// through. Each task is held back until the next one starts, so call the
+// returned func once output has ended: it flushes the last task and reports
+// how many tasks were suppressed.
+func ChangedOnly(onOutput OutputFunc) (OutputFunc, func() int) {
+	var block []string
+	interesting := false
+	suppressed := 0
+
+	flush := func() {
+		if len(block) == 0 {
+			return
+		}
+		if interesting {
+			for _, l := range block {
+				onOutput(l)
+			}
+		} else {
+			suppressed++
+		}
+		block, interesting = nil, false
+	}
+
+	filter := func(line string) {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "TASK ["), strings.HasPrefix(trimmed, "RUNNING HANDLER ["):
+			flush()
+			block = []string{line}
+		case strings.HasPrefix(trimmed, "PLAY "):
+			flush()
+			onOutput(line)
+		case len(block) == 0:
+			onOutput(line)
+		default:
+			block = append(block, line)
+			if !isQuietTaskLine(trimmed) {
+				interesting = true
+			}
+		}
+	}
+	return filter, func() int {
+		flush()
+		return suppressed
+	}
+}
+
+// isQuietTaskLine reports whether a line inside a task block says nothing
+// beyond "nothing to do here".
+func isQuietTaskLine(line string) bool {
+	return line == "" || strings.HasPrefix(line, "ok:") || strings.HasPrefix(line, "skipping:")
+}
@@ -0,0 +1,26 @@
+package ansible
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// RunHook runs command with sh -c after a successful apply, streaming its
+// output through onOutput like a playbook. env is added to flux's own
+// environment. The hook runs in its own process group so cancelling ctx
+// stops anything it started.
+func RunHook(ctx context.Context, command string, env []string, onOutput OutputFunc) error {
+	if !quiet {
+		onOutput(fmt.Sprintf("[HOOK] %s", command))
+		onOutput("")
+	}
+
+	cmd := commandContext(ctx, "sh", "-c", command)
+	cmd.Env = append(os.Environ(), env...)
+	setProcessGroup(cmd)
+	if err := streamCmd(cmd, onOutput); err != nil {
+		return fmt.Errorf("post-run hook failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,261 @@
+package ansible
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jaydubyaeey/flux/internal/platform"
+)
+
+// InstallMethod selects how EnsureInstalled installs Ansible.
+type InstallMethod string
+
+const (
+	// InstallAuto uses the PPA on Ubuntu and falls back to pipx elsewhere or
+	// when the PPA install fails.
+	InstallAuto InstallMethod = "auto"
+	// InstallApt uses apt and the ansible PPA only.
+	InstallApt InstallMethod = "apt"
+	// InstallPipx uses pipx (or pip --user when pipx can't be installed) only.
+	InstallPipx InstallMethod = "pipx"
+)
+
+// DefaultInstallTimeout bounds each install command when no timeout is given.
+const DefaultInstallTimeout = 5 * time.Minute
+
+// installStep is one command of an install attempt. when, if set, is checked
+// just before the step runs and skips it when false, so a step can depend on
+// what earlier steps installed; skipNote, if set, is shown then. A failing
+// optional step doesn't stop the attempt.
+type installStep struct {
+	args     []string
+	when     func() bool
+	skipNote string
+	optional bool
+}
+
+// installAttempt is one way of installing Ansible, tried as a unit.
+type installAttempt struct {
+	name  string
+	steps []installStep
+}
+
+// aptPackage is the apt package that provides ansible-playbook. Set
+// FLUX_ANSIBLE_APT_PKG to use another, e.g. ansible-core.
+func aptPackage() string {
+	if pkg := os.Getenv("FLUX_ANSIBLE_APT_PKG"); pkg != "" {
+		return pkg
+	}
+	return "ansible"
+}
+
+// onPath returns a predicate reporting whether tool is on PATH.
+func onPath(tool string) func() bool {
+	return func() bool {
+		_, err := exec.LookPath(tool)
+		return err == nil
+	}
+}
+
+// missing returns a predicate reporting whether tool is not on PATH.
+func missing(tool string) func() bool {
+	return func() bool { return !onPath(tool)() }
+}
+
+// aptAttempt installs Ansible with apt, from the ansible PPA when
+// apt-add-repository is available. Minimal images may lack it and
+// software-properties-common, which provides it; then the PPA is skipped and
+// the package comes from the distro's own repositories.
+func aptAttempt() installAttempt {
+	return installAttempt{"apt (ansible PPA)", []installStep{
+		{args: []string{"sudo", "apt-get", "update", "-qq"}},
+		{args: []string{"sudo", "apt-get", "install", "-y", "-qq", "software-properties-common"}, when: missing("apt-add-repository"), optional: true},
+		{args: []string{"sudo", "apt-add-repository", "--yes", "--update", "ppa:ansible/ansible"}, when: onPath("apt-add-repository"),
+			skipNote: "apt-add-repository isn't available; installing " + aptPackage() + " from the distro's repositories instead of the PPA"},
+		{args: []string{"sudo", "apt-get", "install", "-y", "-qq", aptPackage()}},
+	}}
+}
+
+// installPlan returns the attempts to make, in order, for method.
+func installPlan(method InstallMethod) []installAttempt {
+	apt := aptAttempt()
+	switch method {
+	case InstallApt:
+		return []installAttempt{apt}
+	case InstallPipx:
+		return []installAttempt{pipxAttempt()}
+	}
+	// The PPA only serves Ubuntu; on Debian and friends apt-add-repository
+	// is missing or adds a repository that doesn't match the release.
+	if platform.DistroID() == "ubuntu" {
+		return []installAttempt{apt, pipxAttempt()}
+	}
+	return []installAttempt{pipxAttempt()}
+}
+
+// pipxAttempt installs Ansible with pipx, installing pipx itself via apt if
+// needed. Without pipx or apt it falls back to pip --user.
+func pipxAttempt() installAttempt {
+	if !onPath("pipx")() && !onPath("apt-get")() {
+		return installAttempt{"pip --user", []installStep{
+			{args: []string{"python3", "-m", "pip", "install", "--user", "ansible"}},
+		}}
+	}
+	return installAttempt{"pipx", []installStep{
+		{args: []string{"sudo", "apt-get", "update", "-qq"}, when: missing("pipx")},
+		{args: []string{"sudo", "apt-get", "install", "-y", "-qq", "pipx"}, when: missing("pipx")},
+		{args: []string{"pipx", "install", "--include-deps", "ansible"}},
+	}}
+}
+
+// EnsureInstalled checks if ansible-playbook is available and installs it if
+// not, trying each method allowed by method in turn. Each command is killed
+// after timeout (DefaultInstallTimeout if zero). Cancelling ctx interrupts
+// the command currently running. The installed version is then checked
+// against MinVersion; see SetStrictVersion. In offline mode (SetOffline) a
+// missing Ansible is ErrOfflineNotInstalled rather than an install.
+func EnsureInstalled(ctx context.Context, method InstallMethod, timeout time.Duration) error {
+	warn := func(line string) { fmt.Fprintln(os.Stderr, line) }
+	if ansibleOnPath() {
+		return checkMinVersion(warn)
+	}
+	if offline {
+		return ErrOfflineNotInstalled
+	}
+
+	if !quiet {
+		fmt.Println("Installing Ansible...")
+	}
+	err := install(ctx, method, timeout, func(ctx context.Context, args []string) error {
+		cmd := commandContext(ctx, args[0], args[1:]...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		return cmd.Run()
+	}, func(line string) { fmt.Println(line) })
+	if err != nil {
+		return err
+	}
+	return checkMinVersion(warn)
+}
+
+// EnsureInstalledStreaming is like EnsureInstalled but sends output through onOutput.
+func EnsureInstalledStreaming(ctx context.Context, method InstallMethod, timeout time.Duration, onOutput OutputFunc) error {
+	if ansibleOnPath() {
+		onOutput("✓ ansible-playbook already installed")
+		return checkMinVersion(onOutput)
+	}
+	if offline {
+		return ErrOfflineNotInstalled
+	}
+
+	onOutput("Installing Ansible...")
+	err := install(ctx, method, timeout, func(ctx context.Context, args []string) error {
+		onOutput(fmt.Sprintf("→ %s", strings.Join(args, " ")))
+		return runCmdStreaming(ctx, args, "", onOutput)
+	}, onOutput)
+	if err != nil {
+		return err
+	}
+	return checkMinVersion(onOutput)
+}
+
+// install runs the attempts from installPlan until one succeeds. If all of
+// them fail, the error lists every method tried and why it failed.
+func install(ctx context.Context, method InstallMethod, timeout time.Duration, run func(context.Context, []string) error, onOutput OutputFunc) error {
+	if timeout <= 0 {
+		timeout = DefaultInstallTimeout
+	}
+	var failures []string
+	for _, attempt := range installPlan(method) {
+		if len(failures) > 0 {
+			onOutput(fmt.Sprintf("Trying %s instead...", attempt.name))
+		}
+		err := runAttempt(ctx, attempt, timeout, run, onOutput)
+		if err == nil && ansibleOnPath() {
+			slog.Info("ansible installed", "method", attempt.name)
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		if err == nil {
+			msg := "the install commands succeeded but ansible-playbook is still not on PATH or in ~/.local/bin"
+			if pkg := os.Getenv("FLUX_ANSIBLE_APT_PKG"); pkg != "" {
+				msg += fmt.Sprintf(" (FLUX_ANSIBLE_APT_PKG=%s may not provide it)", pkg)
+			}
+			err = errors.New(msg)
+		}
+		slog.Warn("ansible install attempt failed", "method", attempt.name, "error", err)
+		failures = append(failures, fmt.Sprintf("%s: %v", attempt.name, err))
+	}
+	return fmt.Errorf("could not install Ansible:\n  %s", strings.Join(failures, "\n  "))
+}
+
+// runAttempt runs each step of attempt whose predicate holds, giving each its
+// own timeout so a hung apt-get update doesn't stall the install forever.
+func runAttempt(ctx context.Context, attempt installAttempt, timeout time.Duration, run func(context.Context, []string) error, onOutput OutputFunc) error {
+	for i, step := range attempt.steps {
+		args := step.args
+		if ctx.Err() != nil {
+			return installCancelled(ctx, attempt.steps, i)
+		}
+		if step.when != nil && !step.when() {
+			if step.skipNote != "" {
+				onOutput(step.skipNote)
+			}
+			continue
+		}
+		stepCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := run(stepCtx, args)
+		timedOut := errors.Is(stepCtx.Err(), context.DeadlineExceeded)
+		cancel()
+		if err == nil {
+			continue
+		}
+		if ctx.Err() != nil {
+			return installCancelled(ctx, attempt.steps, i)
+		}
+		if step.optional && !timedOut {
+			onOutput(fmt.Sprintf("Skipping %q (failed: %v)", strings.Join(args, " "), err))
+			continue
+		}
+		if timedOut {
+			return fmt.Errorf("command %q timed out after %s; check your network connection and retry", strings.Join(args, " "), timeout)
+		}
+		return fmt.Errorf("command %q failed: %w", strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+// installCancelled describes how far the install sequence got before ctx was
+// cancelled, since apt may be left partially configured.
+func installCancelled(ctx context.Context, steps []installStep, step int) error {
+	return fmt.Errorf("ansible install cancelled during %q (%d of %d steps completed); apt may be partially configured, re-run to finish: %w",
+		strings.Join(steps[step].args, " "), step, len(steps), ctx.Err())
+}
+
+// ansibleOnPath reports whether ansible-playbook can be run. pipx and pip
+// --user install into ~/.local/bin, which often isn't on PATH in a fresh
+// shell, so that directory is added to PATH when it holds the binary.
+func ansibleOnPath() bool {
+	if _, err := exec.LookPath("ansible-playbook"); err == nil {
+		return true
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return false
+	}
+	bin := filepath.Join(home, ".local", "bin")
+	if _, err := os.Stat(filepath.Join(bin, "ansible-playbook")); err != nil {
+		return false
+	}
+	os.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return true
+}
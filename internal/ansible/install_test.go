@@ -0,0 +1,59 @@
+package ansible
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunAttemptCancelInterruptsSequence(t *testing.T) {
+	attempt := installAttempt{"fake", []installStep{
+		{args: []string{"true"}},
+		{args: []string{"sleep", "30"}},
+		{args: []string{"echo", "never"}},
+	}}
+
+	var ran []string
+	run := func(ctx context.Context, args []string) error {
+		ran = append(ran, strings.Join(args, " "))
+		return commandContext(ctx, args[0], args[1:]...).Run()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+
+	start := time.Now()
+	err := runAttempt(ctx, attempt, time.Minute, run, func(string) {})
+	if took := time.Since(start); took > 5*time.Second {
+		t.Fatalf("runAttempt took %s after cancel, want it interrupted promptly", took)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want it to wrap context.Canceled", err)
+	}
+	if !strings.Contains(err.Error(), `"sleep 30"`) || !strings.Contains(err.Error(), "1 of 3 steps completed") {
+		t.Errorf("err = %q, want it to name the interrupted step and progress", err)
+	}
+	if want := []string{"true", "sleep 30"}; strings.Join(ran, ",") != strings.Join(want, ",") {
+		t.Errorf("ran %q, want %q (nothing after the cancelled step)", ran, want)
+	}
+}
+
+func TestRunAttemptAlreadyCancelled(t *testing.T) {
+	attempt := installAttempt{"fake", []installStep{{args: []string{"true"}}}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	called := false
+	err := runAttempt(ctx, attempt, time.Minute, func(context.Context, []string) error {
+		called = true
+		return nil
+	}, func(string) {})
+	if called {
+		t.Error("a step ran although the context was already cancelled")
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want it to wrap context.Canceled", err)
+	}
+}
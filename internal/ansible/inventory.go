@@ -0,0 +1,78 @@
+package ansible
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ParseInventoryHosts returns the host names in an INI inventory, in file
+// order and without duplicates. Groups are flattened: hosts are collected
+// from every group, while [group:vars] and [group:children] sections are
+// skipped since they don't name hosts.
+func ParseInventoryHosts(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read inventory: %w", err)
+	}
+	defer f.Close()
+
+	var hosts []string
+	seen := map[string]bool{}
+	inHosts := true // lines before any section are ungrouped hosts
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			_, suffix, _ := strings.Cut(strings.Trim(line, "[]"), ":")
+			inHosts = suffix == ""
+			continue
+		}
+		if !inHosts {
+			continue
+		}
+		for _, host := range expandHostRange(strings.Fields(line)[0]) {
+			if !seen[host] {
+				seen[host] = true
+				hosts = append(hosts, host)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read inventory: %w", err)
+	}
+	return hosts, nil
+}
+
+// hostRange matches a numeric inventory range such as web[01:03].
+var hostRange = regexp.MustCompile(`^(.*)\[(\d+):(\d+)\](.*)$`)
+
+// expandHostRange expands one numeric range pattern into host names,
+// keeping zero padding ("web[01:03]" → web01, web02, web03). Other names
+// are returned as is.
+func expandHostRange(host string) []string {
+	m := hostRange.FindStringSubmatch(host)
+	if m == nil {
+		return []string{host}
+	}
+	from, _ := strconv.Atoi(m[2])
+	to, _ := strconv.Atoi(m[3])
+	if to < from {
+		return []string{host}
+	}
+	width := 0
+	if len(m[2]) > 1 && m[2][0] == '0' {
+		width = len(m[2])
+	}
+	var hosts []string
+	for i := from; i <= to; i++ {
+		hosts = append(hosts, fmt.Sprintf("%s%0*d%s", m[1], width, i, m[4]))
+	}
+	return hosts
+}
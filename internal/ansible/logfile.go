@@ -0,0 +1,60 @@
+package ansible
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// runLog tees playbook output to a file on disk. ansible's stdout and
+// stderr are copied by separate goroutines, so writes are locked.
+type runLog struct {
+	mu sync.Mutex
+	f  *os.File
+	w  *bufio.Writer
+}
+
+// openRunLog creates (or truncates) the log file at path and writes a header
+// describing the run.
+func openRunLog(path string, args []string, tags string) (*runLog, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file: %w", err)
+	}
+	l := &runLog{f: f, w: bufio.NewWriter(f)}
+
+	if tags == "" {
+		tags = "(all)"
+	}
+	fmt.Fprintf(l.w, "# flux run %s\n", time.Now().Format(time.RFC3339))
+	fmt.Fprintf(l.w, "# tags: %s\n", tags)
+	fmt.Fprintf(l.w, "# command: %s\n\n", displayCommand(args))
+	return l, nil
+}
+
+func (l *runLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
+
+// Line appends a single output line.
+func (l *runLog) Line(s string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.w.WriteString(s + "\n")
+}
+
+// Close flushes buffered output and closes the file.
+func (l *runLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	flushErr := l.w.Flush()
+	closeErr := l.f.Close()
+	if flushErr != nil {
+		return flushErr
+	}
+	return closeErr
+}
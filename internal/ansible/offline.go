@@ -0,0 +1,35 @@
+package ansible
+
+import "errors"
+
+// offline is set by SetOffline.
+var offline bool
+
+// ErrOfflineNotInstalled is returned by EnsureInstalled in offline mode
+// when ansible-playbook is missing, instead of trying to download it.
+var ErrOfflineNotInstalled = errors.New("ansible-playbook is not installed and offline mode can't download it; install Ansible first or run without --offline")
+
+// SetOffline switches offline mode: EnsureInstalled only checks that
+// Ansible is present, and playbooks get offline=true so roles skip their
+// downloads.
+func SetOffline(o bool) {
+	offline = o
+}
+
+// Offline reports whether offline mode is on.
+func Offline() bool {
+	return offline
+}
+
+// withOffline adds the offline extra var in offline mode.
+func withOffline(extraVars map[string]interface{}) map[string]interface{} {
+	if !offline {
+		return extraVars
+	}
+	vars := make(map[string]interface{}, len(extraVars)+1)
+	for k, v := range extraVars {
+		vars[k] = v
+	}
+	vars["offline"] = true
+	return vars
+}
@@ -0,0 +1,32 @@
+package ansible
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+)
+
+// ErrKilled means ansible-playbook was terminated by a signal it didn't
+// ask for, which on WSL is almost always the out-of-memory killer.
+var ErrKilled = errors.New("ansible-playbook was killed, most likely because WSL ran out of memory")
+
+// MemoryHint explains how to give WSL more memory; shown with ErrKilled.
+const MemoryHint = `Give WSL more memory in C:\Users\<you>\.wslconfig, e.g.
+  [wsl2]
+  memory=8GB
+then run 'wsl --shutdown' and try again.`
+
+// classifyExit turns a playbook exit caused by a signal (or the shell's
+// 137 for SIGKILL) into ErrKilled. Cancelling ctx also ends ansible with a
+// signal, so err is returned unchanged in that case.
+func classifyExit(ctx context.Context, err error) error {
+	var exitErr *exec.ExitError
+	if err == nil || ctx.Err() != nil || !errors.As(err, &exitErr) {
+		return err
+	}
+	if !exitErr.Exited() || exitErr.ExitCode() == 137 {
+		return fmt.Errorf("%w (%v)", ErrKilled, err)
+	}
+	return err
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package ansible
+
+import "os/exec"
+
+// setProcessGroup is a no-op on platforms without process groups.
+func setProcessGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,91 @@
+package ansible
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// HostRecap holds the PLAY RECAP counters for one host.
+type HostRecap struct {
+	Host        string
+	Ok          int
+	Changed     int
+	Unreachable int
+	Failed      int
+	Skipped     int
+	Rescued     int
+	Ignored     int
+}
+
+// Recap is the parsed PLAY RECAP of an ansible-playbook run.
+type Recap struct {
+	Hosts []HostRecap
+}
+
+// recapLine matches "host : ok=1 changed=0 ..." rows of the recap.
+var recapLine = regexp.MustCompile(`^\s*(\S+)\s*:\s*((?:\w+=\d+\s*)+)$`)
+
+// ParseRecap extracts the last PLAY RECAP section from playbook output. If
+// no recap is found the returned Recap has no hosts.
+func ParseRecap(output string) Recap {
+	lines := strings.Split(output, "\n")
+	start := -1
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "PLAY RECAP") {
+			start = i + 1
+		}
+	}
+	if start < 0 {
+		return Recap{}
+	}
+
+	var recap Recap
+	for _, line := range lines[start:] {
+		m := recapLine.FindStringSubmatch(line)
+		if m == nil {
+			if len(recap.Hosts) > 0 {
+				break
+			}
+			continue
+		}
+		h := HostRecap{Host: m[1]}
+		for _, field := range strings.Fields(m[2]) {
+			key, val, _ := strings.Cut(field, "=")
+			n, _ := strconv.Atoi(val)
+			switch key {
+			case "ok":
+				h.Ok = n
+			case "changed":
+				h.Changed = n
+			case "unreachable":
+				h.Unreachable = n
+			case "failed":
+				h.Failed = n
+			case "skipped":
+				h.Skipped = n
+			case "rescued":
+				h.Rescued = n
+			case "ignored":
+				h.Ignored = n
+			}
+		}
+		recap.Hosts = append(recap.Hosts, h)
+	}
+	return recap
+}
+
+// Total sums the counters across all hosts.
+func (r Recap) Total() HostRecap {
+	var t HostRecap
+	for _, h := range r.Hosts {
+		t.Ok += h.Ok
+		t.Changed += h.Changed
+		t.Unreachable += h.Unreachable
+		t.Failed += h.Failed
+		t.Skipped += h.Skipped
+		t.Rescued += h.Rescued
+		t.Ignored += h.Ignored
+	}
+	return t
+}
@@ -0,0 +1,68 @@
+package ansible
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// secretKeyHints are substrings of extra-var names whose values are masked
+// when the ansible-playbook command line is echoed or logged.
+var secretKeyHints = []string{"password", "passwd", "secret", "token", "api_key", "apikey", "private_key"}
+
+// quiet is set by SetQuiet.
+var quiet bool
+
+// SetQuiet stops RunPlaybook and EnsureInstalled from printing flux's own
+// progress lines, such as the ansible-playbook command echo. Warnings, errors
+// and ansible's output are still shown.
+func SetQuiet(q bool) {
+	quiet = q
+}
+
+// displayCommand renders args for echoing, with secret-looking values in the
+// --extra-vars JSON replaced by "***". The real args are passed to ansible
+// unchanged.
+func displayCommand(args []string) string {
+	shown := make([]string, len(args))
+	copy(shown, args)
+	for i := 0; i+1 < len(shown); i++ {
+		if shown[i] != "--extra-vars" {
+			continue
+		}
+		var vars map[string]interface{}
+		if err := json.Unmarshal([]byte(shown[i+1]), &vars); err != nil {
+			continue
+		}
+		if out, err := json.Marshal(redactVars(vars)); err == nil {
+			shown[i+1] = string(out)
+		}
+	}
+	return "ansible-playbook " + strings.Join(shown, " ")
+}
+
+// redactVars masks the values of secret-looking keys, descending into
+// nested mappings.
+func redactVars(vars map[string]interface{}) map[string]interface{} {
+	for k, v := range vars {
+		if IsSecretKey(k) {
+			vars[k] = "***"
+			continue
+		}
+		if nested, ok := v.(map[string]interface{}); ok {
+			vars[k] = redactVars(nested)
+		}
+	}
+	return vars
+}
+
+// IsSecretKey reports whether an extra var named key looks like it holds a
+// secret, so its value should be masked wherever it's shown.
+func IsSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, hint := range secretKeyHints {
+		if strings.Contains(key, hint) {
+			return true
+		}
+	}
+	return false
+}
@@ -2,40 +2,27 @@ package ansible
 
 import (
 	"bufio"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
-// EnsureInstalled checks if ansible-playbook is available and installs it if not.
-func EnsureInstalled() error {
-	if _, err := exec.LookPath("ansible-playbook"); err == nil {
-		return nil
+// commandContext is exec.CommandContext, but cancellation sends SIGINT first
+// so the child (e.g. apt behind sudo) can clean up before being killed.
+func commandContext(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(os.Interrupt)
 	}
-
-	fmt.Println("Installing Ansible...")
-
-	cmds := [][]string{
-		{"sudo", "apt-get", "update", "-qq"},
-		{"sudo", "apt-get", "install", "-y", "-qq", "software-properties-common"},
-		{"sudo", "apt-add-repository", "--yes", "--update", "ppa:ansible/ansible"},
-		{"sudo", "apt-get", "install", "-y", "-qq", "ansible"},
-	}
-
-	for _, args := range cmds {
-		cmd := exec.Command(args[0], args[1:]...)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("command %q failed: %w", strings.Join(args, " "), err)
-		}
-	}
-
-	return nil
+	cmd.WaitDelay = 5 * time.Second
+	return cmd
 }
 
 // FindAnsibleDir locates the ansible/ directory by checking:
@@ -47,7 +34,9 @@ func FindAnsibleDir() (string, error) {
 	candidates := []string{}
 
 	// Standard installation directory
-	if home, err := os.UserHomeDir(); err == nil {
+	if dir := os.Getenv("FLUX_INSTALL_DIR"); dir != "" {
+		candidates = append(candidates, filepath.Join(dir, "ansible"))
+	} else if home, err := os.UserHomeDir(); err == nil {
 		candidates = append(candidates, filepath.Join(home, ".local", "share", "flux", "ansible"))
 	}
 
@@ -84,178 +73,283 @@ func FindAnsibleDir() (string, error) {
 	return "", fmt.Errorf("cannot find ansible/ directory containing playbook.yml")
 }
 
-// RunPlaybook executes ansible-playbook with the given options.
-func RunPlaybook(ansibleDir string, extraVars map[string]interface{}, tags string, dryRun bool) error {
-	playbook := filepath.Join(ansibleDir, "playbook.yml")
-	inventory := filepath.Join(ansibleDir, "inventory.ini")
-
-	if _, err := os.Stat(playbook); err != nil {
-		return fmt.Errorf("playbook not found: %s", playbook)
+// RunPlaybook executes ansible-playbook for opts with the terminal attached,
+// so ansible can prompt for the become password (and for each task with
+// opts.Step). If opts.LogFile is set, all output is also written to that
+// file, and opts.Capture gets a copy of stdout. Cancelling ctx interrupts
+// ansible-playbook.
+func RunPlaybook(ctx context.Context, opts RunOptions) error {
+	args, cleanup, err := buildPlaybookArgs(opts, stderrWarn)
+	if err != nil {
+		return err
 	}
+	defer cleanup()
 
-	args := []string{
-		playbook,
-		"-i", inventory,
-		"--connection=local",
+	if !quiet {
+		mode := "APPLY"
+		if opts.DryRun {
+			mode = "DRY RUN (check mode)"
+		}
+		fmt.Printf("[%s] %s\n\n", mode, displayCommand(args))
 	}
 
-	if len(extraVars) > 0 {
-		varsJSON, err := json.Marshal(extraVars)
+	// ansible's output only goes through a pipe when something needs a
+	// copy; otherwise it keeps the terminal
+	stdouts, stderrs := []io.Writer{os.Stdout}, []io.Writer{os.Stderr}
+	if opts.Capture != nil {
+		stdouts = append(stdouts, &plainWriter{w: opts.Capture})
+	}
+	if opts.LogFile != "" {
+		log, err := openRunLog(opts.LogFile, args, opts.Tags)
 		if err != nil {
-			return fmt.Errorf("failed to marshal extra vars: %w", err)
+			return err
 		}
-		args = append(args, "--extra-vars", string(varsJSON))
+		defer log.Close()
+		stdouts = append(stdouts, &plainWriter{w: log})
+		stderrs = append(stderrs, &plainWriter{w: log})
 	}
 
-	if tags != "" {
-		args = append(args, "--tags", tags)
+	cmd := commandContext(ctx, "ansible-playbook", args...)
+	cmd.Stdout = tee(stdouts)
+	cmd.Stderr = tee(stderrs)
+	cmd.Stdin = os.Stdin
+	cmd.Dir = opts.AnsibleDir
+	cmd.Env = ansibleEnv(nil)
+	if len(stdouts) > 1 && keepColor() {
+		// The copies are written without it
+		cmd.Env = append(cmd.Env, "ANSIBLE_FORCE_COLOR=1")
 	}
 
-	if dryRun {
-		args = append(args, "--check", "--diff")
-	}
+	start := logRunStart(args)
+	return logRunEnd(start, classifyExit(ctx, cmd.Run()))
+}
 
-	// Ask for become password if not root
-	if os.Getuid() != 0 {
-		args = append(args, "--ask-become-pass")
+// ansibleEnv returns env, or the current environment when env is nil, with
+// the UTF-8 locale ansible insists on and then extra.
+func ansibleEnv(env []string, extra ...string) []string {
+	if env == nil {
+		env = os.Environ()
 	}
+	env = append(env, "LC_ALL=C.UTF-8", "LANG=C.UTF-8")
+	return append(env, extra...)
+}
 
-	mode := "APPLY"
-	if dryRun {
-		mode = "DRY RUN (check mode)"
+// tee writes to all of ws. A lone *os.File is returned as is, so a child
+// process given it writes to the terminal directly rather than a pipe.
+func tee(ws []io.Writer) io.Writer {
+	if len(ws) == 1 {
+		return ws[0]
 	}
-	fmt.Printf("[%s] ansible-playbook %s\n\n", mode, strings.Join(args, " "))
-
-	cmd := exec.Command("ansible-playbook", args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin
-	cmd.Dir = ansibleDir
-	cmd.Env = append(os.Environ(), "LC_ALL=C.UTF-8", "LANG=C.UTF-8")
-
-	return cmd.Run()
+	return io.MultiWriter(ws...)
 }
 
-func isAnsibleDir(dir string) bool {
-	info, err := os.Stat(filepath.Join(dir, "playbook.yml"))
-	return err == nil && !info.IsDir()
+// logRunStart records in flux's log that ansible-playbook is starting,
+// with the secrets in args masked, and returns the start time.
+func logRunStart(args []string) time.Time {
+	slog.Info("ansible-playbook started", "command", displayCommand(args))
+	return time.Now()
 }
 
-// OutputFunc is called for each line of output from a streaming command.
-type OutputFunc func(line string)
-
-// EnsureInstalledStreaming is like EnsureInstalled but sends output through onOutput.
-func EnsureInstalledStreaming(onOutput OutputFunc) error {
-	if _, err := exec.LookPath("ansible-playbook"); err == nil {
-		onOutput("✓ ansible-playbook already installed")
-		return nil
+// logRunEnd records how a run started at start ended, and returns err.
+func logRunEnd(start time.Time, err error) error {
+	took := time.Since(start).Round(time.Millisecond).String()
+	if err != nil {
+		slog.Error("ansible-playbook failed", "duration", took, "error", err)
+	} else {
+		slog.Info("ansible-playbook finished", "duration", took)
 	}
+	return err
+}
 
-	onOutput("Installing Ansible...")
+// SyntaxCheck runs ansible-playbook --syntax-check for the run opts
+// describes, returning the combined output in the error on failure.
+func SyntaxCheck(opts RunOptions) error {
+	out, err := inspectPlaybook(opts, "--syntax-check")
+	if errors.As(err, new(*exec.ExitError)) {
+		return fmt.Errorf("syntax check failed: %w\n%s", err, out)
+	}
+	return err
+}
 
-	cmds := [][]string{
-		{"sudo", "apt-get", "update", "-qq"},
-		{"sudo", "apt-get", "install", "-y", "-qq", "software-properties-common"},
-		{"sudo", "apt-add-repository", "--yes", "--update", "ppa:ansible/ansible"},
-		{"sudo", "apt-get", "install", "-y", "-qq", "ansible"},
+// ListTasks runs ansible-playbook --list-tasks and returns the names of the
+// tasks the run opts describes would execute.
+func ListTasks(opts RunOptions) ([]string, error) {
+	out, err := inspectPlaybook(opts, "--list-tasks")
+	if errors.As(err, new(*exec.ExitError)) {
+		return nil, fmt.Errorf("list tasks failed: %w\n%s", err, out)
 	}
+	if err != nil {
+		return nil, err
+	}
+	return parseTaskList(out), nil
+}
 
-	for _, args := range cmds {
-		onOutput(fmt.Sprintf("→ %s", strings.Join(args, " ")))
-		if err := runCmdStreaming(args, "", onOutput); err != nil {
-			return fmt.Errorf("command %q failed: %w", strings.Join(args, " "), err)
-		}
+// inspectPlaybook runs ansible-playbook with the arguments of the run opts
+// describes plus mode, one that only reads the playbook, and returns the
+// combined output. Nothing runs, so no become password is asked for, and
+// warnings are left to the real run.
+func inspectPlaybook(opts RunOptions, mode string) (string, error) {
+	if _, err := exec.LookPath("ansible-playbook"); err != nil {
+		return "", fmt.Errorf("ansible-playbook not found; run setup once to install Ansible")
+	}
+	opts.DryRun, opts.Step, opts.Verbosity = false, false, 0
+	opts.NoBecomePass = true
+	args, cleanup, err := buildPlaybookArgs(opts, func(string) {})
+	defer cleanup()
+	if err != nil {
+		return "", err
 	}
 
-	return nil
+	cmd := exec.Command("ansible-playbook", append(args, mode)...)
+	cmd.Dir = opts.AnsibleDir
+	cmd.Env = ansibleEnv(nil, "ANSIBLE_NOCOLOR=1")
+	out, err := cmd.CombinedOutput()
+	return strings.TrimSpace(string(out)), err
 }
 
-// RunPlaybookStreaming executes ansible-playbook, sending output line-by-line
-// through onOutput. If becomePass is non-empty it is piped to ansible's stdin
-// in place of --ask-become-pass.
-func RunPlaybookStreaming(ansibleDir string, extraVars map[string]interface{}, tags string, dryRun bool, becomePass string, onOutput OutputFunc) error {
-	playbook := filepath.Join(ansibleDir, "playbook.yml")
-	inventory := filepath.Join(ansibleDir, "inventory.ini")
-
-	if _, err := os.Stat(playbook); err != nil {
-		return fmt.Errorf("playbook not found: %s", playbook)
+// parseTaskList extracts task names from --list-tasks output, which looks like:
+//
+//	play #1 (localhost): Flux - WSL Setup	TAGS: []
+//	  tasks:
+//	    base : Install packages	TAGS: [base]
+func parseTaskList(output string) []string {
+	var tasks []string
+	inTasks := false
+	for _, line := range strings.Split(output, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "tasks:":
+			inTasks = true
+		case strings.HasPrefix(trimmed, "play #"), trimmed == "":
+			inTasks = false
+		case inTasks:
+			name, _, _ := strings.Cut(trimmed, "\tTAGS:")
+			tasks = append(tasks, strings.TrimSpace(name))
+		}
 	}
+	return tasks
+}
 
-	args := []string{
-		playbook,
-		"-i", inventory,
-		"--connection=local",
+// targetArgs returns the inventory and connection arguments for a run. The
+// default (connection "" or "local") provisions this machine through the
+// bundled localhost inventory. Any other connection, such as "ssh", needs an
+// explicit inventory describing the remote hosts. limit and remoteUser map to
+// --limit and --user; become-password handling is the same either way.
+func targetArgs(ansibleDir, inventory, connection, limit, remoteUser string) ([]string, error) {
+	if connection == "" {
+		connection = "local"
 	}
-
-	if len(extraVars) > 0 {
-		varsJSON, err := json.Marshal(extraVars)
-		if err != nil {
-			return fmt.Errorf("failed to marshal extra vars: %w", err)
-		}
-		args = append(args, "--extra-vars", string(varsJSON))
+	if connection != "local" && inventory == "" {
+		return nil, fmt.Errorf("--connection %s needs an --inventory listing the remote hosts", connection)
+	}
+	inventory, err := resolveInventory(ansibleDir, inventory)
+	if err != nil {
+		return nil, err
 	}
+	args := []string{"-i", inventory, "--connection=" + connection}
+	if limit != "" {
+		args = append(args, "--limit", limit)
+	}
+	if remoteUser != "" {
+		args = append(args, "--user", remoteUser)
+	}
+	return args, nil
+}
 
-	if tags != "" {
-		args = append(args, "--tags", tags)
+// withTargetHosts points the play at every inventory host for remote
+// connections; the playbook targets localhost by default.
+func withTargetHosts(extraVars map[string]interface{}, connection string) map[string]interface{} {
+	if connection == "" || connection == "local" {
+		return extraVars
+	}
+	vars := make(map[string]interface{}, len(extraVars)+1)
+	for k, v := range extraVars {
+		vars[k] = v
 	}
+	vars["flux_hosts"] = "all"
+	return vars
+}
 
-	if dryRun {
-		args = append(args, "--check", "--diff")
+// resolveInventory returns the inventory path to pass to -i. An empty
+// inventory means ansible/inventory.ini; relative paths are resolved against
+// the working directory since ansible itself runs from ansibleDir.
+func resolveInventory(ansibleDir, inventory string) (string, error) {
+	if inventory == "" {
+		inventory = filepath.Join(ansibleDir, "inventory.ini")
+	} else if abs, err := filepath.Abs(inventory); err == nil {
+		inventory = abs
+	}
+	if info, err := os.Stat(inventory); err != nil || info.IsDir() {
+		return "", fmt.Errorf("inventory file not found: %s", inventory)
 	}
+	return inventory, nil
+}
 
-	// If we have a password, write it to a temp file for --become-password-file
-	if os.Getuid() != 0 {
-		if becomePass != "" {
-			tmpFile, err := os.CreateTemp("", "flux-become-*")
-			if err != nil {
-				return fmt.Errorf("failed to create temp password file: %w", err)
-			}
-			defer os.Remove(tmpFile.Name())
+func isAnsibleDir(dir string) bool {
+	info, err := os.Stat(filepath.Join(dir, "playbook.yml"))
+	return err == nil && !info.IsDir()
+}
 
-			if _, err := tmpFile.WriteString(becomePass); err != nil {
-				tmpFile.Close()
-				return fmt.Errorf("failed to write temp password file: %w", err)
-			}
-			tmpFile.Close()
+// OutputFunc is called for each line of output from a streaming command.
+type OutputFunc func(line string)
 
-			// Restrict permissions to owner-only
-			if err := os.Chmod(tmpFile.Name(), 0600); err != nil {
-				return fmt.Errorf("failed to chmod temp password file: %w", err)
-			}
+// RunPlaybookStreaming executes ansible-playbook for opts, sending output
+// line-by-line through onOutput. There is no terminal to prompt on, so
+// opts.Step is ignored and opts.BecomePass should be set unless running as
+// root. If opts.LogFile is set, every line is also written to that file.
+// Cancelling ctx sends SIGINT to ansible's process group; the temp password
+// file is still removed.
+func RunPlaybookStreaming(ctx context.Context, opts RunOptions, onOutput OutputFunc) error {
+	opts.Step = false
+	args, cleanup, err := buildPlaybookArgs(opts, onOutput)
+	if err != nil {
+		return err
+	}
+	// Removes the temp become password file, also after cancellation
+	defer cleanup()
 
-			args = append(args, "--become-password-file", tmpFile.Name())
-		} else {
-			args = append(args, "--ask-become-pass")
+	if opts.LogFile != "" {
+		log, err := openRunLog(opts.LogFile, args, opts.Tags)
+		if err != nil {
+			return err
+		}
+		defer log.Close()
+		display := onOutput
+		onOutput = func(line string) {
+			log.Line(line)
+			display(line)
 		}
 	}
 
 	mode := "APPLY"
-	if dryRun {
+	if opts.DryRun {
 		mode = "DRY RUN (check mode)"
 	}
-	onOutput(fmt.Sprintf("[%s] ansible-playbook %s", mode, strings.Join(args, " ")))
+	onOutput(fmt.Sprintf("[%s] %s", mode, displayCommand(args)))
 	onOutput("")
 
-	return runCmdStreaming([]string{"ansible-playbook"}, ansibleDir, onOutput, args[0:]...)
+	cmd := commandContext(ctx, "ansible-playbook", args...)
+	cmd.Dir = opts.AnsibleDir
+	// Run in its own process group so cancellation reaches ansible's workers
+	setProcessGroup(cmd)
+	start := logRunStart(args)
+	return logRunEnd(start, classifyExit(ctx, streamCmd(cmd, onOutput)))
 }
 
 // runCmdStreaming runs a command, piping merged stdout+stderr line-by-line to onOutput.
-// cmdAndArgs is the set of arguments; if extraArgs is provided they are used as the
-// full arg list instead of cmdAndArgs[1:].
-func runCmdStreaming(cmdAndArgs []string, dir string, onOutput OutputFunc, extraArgs ...string) error {
-	name := cmdAndArgs[0]
-	var args []string
-	if len(extraArgs) > 0 {
-		args = extraArgs
-	} else {
-		args = cmdAndArgs[1:]
-	}
-
-	cmd := exec.Command(name, args...)
+func runCmdStreaming(ctx context.Context, cmdAndArgs []string, dir string, onOutput OutputFunc) error {
+	cmd := commandContext(ctx, cmdAndArgs[0], cmdAndArgs[1:]...)
 	if dir != "" {
 		cmd.Dir = dir
 	}
-	cmd.Env = append(os.Environ(), "LC_ALL=C.UTF-8", "LANG=C.UTF-8", "ANSIBLE_FORCE_COLOR=0", "ANSIBLE_NOCOLOR=1")
+	return streamCmd(cmd, onOutput)
+}
+
+// streamCmd starts cmd and pipes its merged stdout+stderr line-by-line to
+// onOutput, returning once the command exits and all output is consumed.
+// A cmd.Env set by the caller is kept and extended.
+func streamCmd(cmd *exec.Cmd, onOutput OutputFunc) error {
+	cmd.Env = ansibleEnv(cmd.Env, "ANSIBLE_FORCE_COLOR=0", "ANSIBLE_NOCOLOR=1")
 
 	// Merge stdout and stderr into a single pipe
 	pr, pw := io.Pipe()
@@ -0,0 +1,84 @@
+package ansible
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeAnsible puts an ansible-playbook on PATH that lists one task per
+// argument it was given, and fails with --syntax-check when FAKE_FAIL is set.
+func fakeAnsible(t *testing.T) {
+	t.Helper()
+	bin := t.TempDir()
+	script := `#!/bin/sh
+for a in "$@"; do
+	[ "$a" = --syntax-check ] && [ -n "$FAKE_FAIL" ] && { echo "ERROR! bad yaml"; exit 4; }
+done
+echo "play #1 (localhost): Flux	TAGS: []"
+echo "  tasks:"
+for a in "$@"; do printf '    %s\tTAGS: []\n' "$a"; done
+`
+	if err := os.WriteFile(filepath.Join(bin, "ansible-playbook"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestListTasksUsesRunArgs(t *testing.T) {
+	fakeAnsible(t)
+	dir := testAnsibleDir(t)
+	inventory := filepath.Join(t.TempDir(), "hosts.ini")
+	if err := os.WriteFile(inventory, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	args, err := ListTasks(RunOptions{
+		AnsibleDir:  dir,
+		Inventory:   inventory,
+		Connection:  "ssh",
+		Limit:       "web1",
+		Tags:        "golang",
+		SkipTags:    "podman",
+		DryRun:      true,
+		Step:        true,
+		Verbosity:   2,
+		BecomePass:  "pw",
+		Passthrough: []string{"--start-at-task", "Install go"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(args, " ")
+	for _, want := range []string{"-i " + inventory, "--connection=ssh", "--limit web1", "--tags golang", "--skip-tags podman", "--start-at-task Install go", "--list-tasks"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("ansible-playbook got %q, want %q in it", got, want)
+		}
+	}
+	for _, unwanted := range []string{"--check", "--step", "-vv", "--ask-become-pass", "--become-password-file"} {
+		if _, ok := flagValue(args, unwanted); ok {
+			t.Errorf("ansible-playbook got %s for --list-tasks: %q", unwanted, got)
+		}
+	}
+}
+
+func TestSyntaxCheck(t *testing.T) {
+	fakeAnsible(t)
+	dir := testAnsibleDir(t)
+	if err := SyntaxCheck(RunOptions{AnsibleDir: dir}); err != nil {
+		t.Errorf("SyntaxCheck: %v", err)
+	}
+
+	t.Setenv("FAKE_FAIL", "1")
+	err := SyntaxCheck(RunOptions{AnsibleDir: dir})
+	if err == nil || !strings.Contains(err.Error(), "syntax check failed") || !strings.Contains(err.Error(), "bad yaml") {
+		t.Errorf("err = %v, want the failure with ansible's output", err)
+	}
+
+	// Bad targets are reported as they are, not as a syntax error
+	err = SyntaxCheck(RunOptions{AnsibleDir: dir, Inventory: filepath.Join(dir, "missing.ini")})
+	if err == nil || strings.Contains(err.Error(), "syntax check failed") {
+		t.Errorf("err = %v, want the inventory error", err)
+	}
+}
@@ -0,0 +1,23 @@
+package ansible
+
+import (
+	"fmt"
+	"os"
+)
+
+// checkVaultPasswordFile makes sure path is a readable regular file before
+// it is handed to ansible. A file readable by group or others isn't fatal,
+// but the returned warning says how to tighten it.
+func checkVaultPasswordFile(path string) (warning string, err error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("vault password file: %w", err)
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("vault password file %s is a directory", path)
+	}
+	if perm := info.Mode().Perm(); perm&0077 != 0 {
+		return fmt.Sprintf("Warning: vault password file %s has mode %04o; run 'chmod 600 %s' so only you can read it", path, perm, path), nil
+	}
+	return "", nil
+}
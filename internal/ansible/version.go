@@ -0,0 +1,85 @@
+package ansible
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MinVersion is the oldest ansible-core release the bundled roles support.
+const MinVersion = "2.14"
+
+// ErrVersionTooOld is returned by EnsureInstalled under SetStrictVersion when
+// ansible-playbook is older than MinVersion.
+var ErrVersionTooOld = errors.New("ansible-playbook is too old")
+
+// strictVersion is set by SetStrictVersion.
+var strictVersion bool
+
+// SetStrictVersion makes EnsureInstalled fail, rather than warn, when the
+// installed ansible-playbook is older than MinVersion.
+func SetStrictVersion(strict bool) {
+	strictVersion = strict
+}
+
+// versionRe matches both "ansible-playbook [core 2.16.3]" (ansible-core) and
+// the older "ansible-playbook 2.9.6" first line of --version.
+var versionRe = regexp.MustCompile(`ansible-playbook \[?(?:core )?(\d+(?:\.\d+)*)`)
+
+// Version runs ansible-playbook --version and returns the version it reports,
+// e.g. "2.16.3".
+func Version() (string, error) {
+	out, err := exec.Command("ansible-playbook", "--version").CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("ansible-playbook --version failed: %w", err)
+	}
+	m := versionRe.FindStringSubmatch(string(out))
+	if m == nil {
+		first, _, _ := strings.Cut(string(out), "\n")
+		return "", fmt.Errorf("unrecognised ansible-playbook --version output %q", first)
+	}
+	return m[1], nil
+}
+
+// VersionAtLeast reports whether dotted version v is at least min. Missing
+// components count as zero, so "2.14" equals "2.14.0".
+func VersionAtLeast(v, min string) bool {
+	a, b := strings.Split(v, "."), strings.Split(min, ".")
+	for i := 0; i < len(a) || i < len(b); i++ {
+		x, y := versionPart(a, i), versionPart(b, i)
+		if x != y {
+			return x > y
+		}
+	}
+	return true
+}
+
+func versionPart(parts []string, i int) int {
+	if i >= len(parts) {
+		return 0
+	}
+	n, _ := strconv.Atoi(parts[i])
+	return n
+}
+
+// checkMinVersion compares the installed ansible-playbook against MinVersion.
+// An old version is reported through warn, or returned as an error when
+// SetStrictVersion is on. A version that can't be read is only warned about.
+func checkMinVersion(warn func(string)) error {
+	v, err := Version()
+	if err != nil {
+		warn(fmt.Sprintf("Warning: could not determine the ansible version: %v", err))
+		return nil
+	}
+	if VersionAtLeast(v, MinVersion) {
+		return nil
+	}
+	if strictVersion {
+		return fmt.Errorf("%w: %s is older than %s; upgrade Ansible (e.g. pipx upgrade ansible)", ErrVersionTooOld, v, MinVersion)
+	}
+	warn(fmt.Sprintf("Warning: ansible-playbook %s is older than %s, which some roles need; upgrade Ansible (e.g. pipx upgrade ansible)", v, MinVersion))
+	return nil
+}
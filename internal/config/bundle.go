@@ -0,0 +1,133 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// BundleVersion is the schema version written by ExportBundle. Bump it when
+// the layout changes and teach migrateBundle to read the old one.
+const BundleVersion = 1
+
+// Bundle is a portable snapshot of a flux setup, for carrying it to a new
+// machine: the effective config, the profile it came from and the role
+// selection. Unlike a profile it doesn't stay linked to anything; the
+// config is stored with any extends already resolved.
+type Bundle struct {
+	Version int     `yaml:"version"`
+	Profile string  `yaml:"profile,omitempty"`
+	Config  *Config `yaml:"config"`
+	State   *State  `yaml:"state,omitempty"`
+}
+
+// ExportBundle writes the current config, active profile and state to path.
+func ExportBundle(path string) error {
+	cfg, err := Load()
+	if err != nil {
+		return err
+	}
+	st, err := LoadState()
+	if err != nil {
+		return fmt.Errorf("state: %w", err)
+	}
+	resolved := *cfg
+	resolved.Extends = ""
+	b := Bundle{Version: BundleVersion, Config: &resolved}
+	if name := ActiveProfile(); name != DefaultProfile {
+		b.Profile = name
+	}
+	if len(st.Roles) > 0 {
+		b.State = st
+	}
+	data, err := yaml.Marshal(b)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, 0644)
+}
+
+// ReadBundle reads and validates a bundle written by ExportBundle, migrating
+// older versions.
+func ReadBundle(path string) (*Bundle, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var head struct {
+		Version int `yaml:"version"`
+	}
+	if err := yaml.Unmarshal(data, &head); err != nil {
+		return nil, fmt.Errorf("invalid bundle: %w", err)
+	}
+	if head.Version > BundleVersion {
+		return nil, fmt.Errorf("bundle version %d is newer than this flux supports (%d); update flux first", head.Version, BundleVersion)
+	}
+	b, err := migrateBundle(head.Version, data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bundle: %w", err)
+	}
+	if b.Config == nil {
+		return nil, fmt.Errorf("invalid bundle: no config in %s", path)
+	}
+	if err := b.Config.Validate(); err != nil {
+		return nil, fmt.Errorf("bundle config: %w", err)
+	}
+	if b.Profile != "" {
+		if err := validateProfileName(b.Profile); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// migrateBundle decodes a bundle of the given version into the current
+// layout. Version 0 (no version key) is a plain config file, so a bare
+// config.yaml can be imported too.
+func migrateBundle(version int, data []byte) (*Bundle, error) {
+	switch version {
+	case 0:
+		cfg := DefaultConfig()
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			return nil, err
+		}
+		if cfg.Extends != "" {
+			return nil, fmt.Errorf("a plain config that extends %s isn't self-contained; export a bundle instead", cfg.Extends)
+		}
+		return &Bundle{Version: BundleVersion, Config: cfg}, nil
+	}
+	var b Bundle
+	if err := yaml.Unmarshal(data, &b); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// ImportTarget returns the config file ImportBundle would write for b.
+func ImportTarget(b *Bundle) string {
+	if b.Profile != "" {
+		return ProfilePath(b.Profile)
+	}
+	return FilePath()
+}
+
+// ImportBundle restores b: the config is saved to its profile, which is
+// made active, or to the current config file, and the state replaces the
+// local one when the bundle has any.
+func ImportBundle(b *Bundle) error {
+	if b.Profile != "" {
+		if err := SaveProfile(b.Profile, b.Config); err != nil {
+			return err
+		}
+		if err := UseProfile(b.Profile); err != nil {
+			return err
+		}
+	} else if err := Save(b.Config); err != nil {
+		return err
+	}
+	if b.State != nil {
+		return SaveState(b.State)
+	}
+	return nil
+}
@@ -5,7 +5,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -17,36 +20,101 @@ const (
 
 // Config holds all user-specific settings passed to Ansible as extra vars.
 type Config struct {
-	Username      string   `yaml:"username"`
-	Email         string   `yaml:"email"`
-	GitName       string   `yaml:"git_name"`
-	GitEmail      string   `yaml:"git_email"`
-	GitHTTPS      bool     `yaml:"git_https"`
-	DefaultShell  string   `yaml:"default_shell"`
-	InstallPodman bool     `yaml:"install_podman"`
-	InstallBun    bool     `yaml:"install_bun"`
-	InstallGo     bool     `yaml:"install_go"`
-	GoVersion     string   `yaml:"go_version,omitempty"`
-	InstallDotnet bool     `yaml:"install_dotnet"`
-	DotnetVersion string   `yaml:"dotnet_version,omitempty"`
-	InstallPython bool     `yaml:"install_python"`
-	PythonVersion string   `yaml:"python_version,omitempty"`
-	InstallK9s    bool     `yaml:"install_k9s"`
-	ExtraPackages []string `yaml:"extra_packages,omitempty"`
+	// Extends names a base config file this one overlays (see extends.go).
+	Extends string `yaml:"extends,omitempty"`
+
+	Username        string   `yaml:"username"`
+	Email           string   `yaml:"email"`
+	GitName         string   `yaml:"git_name"`
+	GitEmail        string   `yaml:"git_email"`
+	GitHTTPS        bool     `yaml:"git_https"`
+	DefaultShell    string   `yaml:"default_shell"`
+	InstallPodman   bool     `yaml:"install_podman"`
+	PodmanWSLDistro string   `yaml:"podman_wsl_distro,omitempty"`
+	PodmanWSLHost   string   `yaml:"podman_wsl_host,omitempty"`
+	PodmanWSLPort   string   `yaml:"podman_wsl_port,omitempty"`
+	InstallBun      bool     `yaml:"install_bun"`
+	InstallGo       bool     `yaml:"install_go"`
+	GoVersion       string   `yaml:"go_version,omitempty"`
+	InstallDotnet   bool     `yaml:"install_dotnet"`
+	DotnetVersion   string   `yaml:"dotnet_version,omitempty"`
+	InstallPython   bool     `yaml:"install_python"`
+	PythonVersion   string   `yaml:"python_version,omitempty"`
+	InstallK9s      bool     `yaml:"install_k9s"`
+	ExtraPackages   []string `yaml:"extra_packages,omitempty"`
+
+	// AnsibleInstallMethod chooses how a missing Ansible is installed:
+	// "auto" (default), "apt" or "pipx".
+	AnsibleInstallMethod string `yaml:"ansible_install_method,omitempty"`
+
+	// InstallTimeout bounds each Ansible install command, as a Go duration
+	// such as "10m". Empty means the 5 minute default.
+	InstallTimeout string `yaml:"install_timeout,omitempty"`
+
+	// VaultPasswordFile, if set, is passed to ansible-playbook as
+	// --vault-password-file so vault-encrypted vars can be used.
+	VaultPasswordFile string `yaml:"vault_password_file,omitempty"`
+
+	// LogFile, if set, receives a copy of all playbook output.
+	LogFile string `yaml:"log_file,omitempty"`
+
+	// CheckModeExcludedRoles lists role tags that don't support --check.
+	// They are skipped during dry runs but applied normally.
+	CheckModeExcludedRoles []string `yaml:"check_mode_excluded_roles,omitempty"`
+
+	// DefaultDeselected lists role tags that start unchecked on the TUI's
+	// role screen. Unlike the install_* flags it only changes the default
+	// selection; the roles can still be checked and run.
+	DefaultDeselected []string `yaml:"default_deselected,omitempty"`
+
+	// DisablePasswordCache makes the TUI ask for the sudo password on every
+	// run instead of remembering it until flux exits.
+	DisablePasswordCache bool `yaml:"disable_password_cache,omitempty"`
+
+	// NoBecomePass stops flux asking for the sudo password, for users with
+	// passwordless (NOPASSWD) sudo. flux also detects that on its own for
+	// local runs; this is for when the detection can't tell.
+	NoBecomePass bool `yaml:"no_become_pass,omitempty"`
+
+	// SafeMode makes every run a dry run unless the apply is confirmed
+	// explicitly: --apply on the command line, typing "apply" in the TUI.
+	SafeMode bool `yaml:"safe_mode,omitempty"`
+
+	// Forks is how many hosts Ansible works on at once (--forks). Zero
+	// keeps Ansible's default of 5; only multi-host runs notice.
+	Forks int `yaml:"forks,omitempty"`
+
+	// PostRunHook is a shell command run after a successful apply, with
+	// the config values in FLUX_* environment variables (see HookEnv).
+	PostRunHook string `yaml:"post_run_hook,omitempty"`
+}
+
+// emailPattern is a deliberately loose user@domain.tld check.
+var emailPattern = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+
+// isValidEmail reports whether s looks like an email address.
+func isValidEmail(s string) bool {
+	return emailPattern.MatchString(s)
 }
 
 // validShells is the set of supported shell values.
-var validShells = map[string]bool{"bash": true, "zsh": true}
+var validShells = map[string]bool{"bash": true, "zsh": true, "fish": true}
+
+// validInstallMethods is the set of supported ansible_install_method values.
+var validInstallMethods = map[string]bool{"": true, "auto": true, "apt": true, "pipx": true}
 
 // DefaultConfig returns sensible defaults.
 func DefaultConfig() *Config {
 	return &Config{
-		Username:      whoami(),
-		GitHTTPS:      true,
-		DefaultShell:  "zsh",
-		InstallPodman: true,
-		InstallBun:    true,
-		InstallGo:     true, GoVersion: "latest", InstallDotnet: true,
+		Username:        whoami(),
+		GitHTTPS:        true,
+		DefaultShell:    "zsh",
+		InstallPodman:   true,
+		PodmanWSLDistro: "podman-machine",
+		PodmanWSLHost:   "localhost",
+		PodmanWSLPort:   "22",
+		InstallBun:      true,
+		InstallGo:       true, GoVersion: "latest", InstallDotnet: true,
 		DotnetVersion: "latest",
 		InstallPython: true,
 		PythonVersion: "latest",
@@ -55,23 +123,56 @@ func DefaultConfig() *Config {
 	}
 }
 
-// FilePath returns the full path to the config file.
+// pathOverride is set by SetFilePath (the --config flag) and takes
+// precedence over FLUX_CONFIG and the default location.
+var pathOverride string
+
+// SetFilePath overrides the config file location for this process.
+// Relative paths are resolved against the working directory; an empty path
+// removes the override.
+func SetFilePath(path string) {
+	if path == "" {
+		pathOverride = ""
+		return
+	}
+	pathOverride = absPath(path)
+}
+
+// FilePath returns the full path to the config file. It honors, in order,
+// SetFilePath, the FLUX_CONFIG environment variable, the active profile,
+// and the default ~/.config/flux/config.yaml.
 func FilePath() string {
-	home, _ := os.UserHomeDir()
-	return filepath.Join(home, configDir, configFile)
+	path, _ := resolvePath()
+	return path
 }
 
-// Load reads the config from disk. Returns error if it doesn't exist.
-func Load() (*Config, error) {
-	data, err := os.ReadFile(FilePath())
-	if err != nil {
-		return nil, err
+// FilePathSource reports where FilePath came from: "flag", "env", "profile"
+// or "default".
+func FilePathSource() string {
+	_, source := resolvePath()
+	return source
+}
+
+func resolvePath() (path, source string) {
+	if pathOverride != "" {
+		return pathOverride, "flag"
 	}
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		return nil, fmt.Errorf("invalid config: %w", err)
+	if env := os.Getenv("FLUX_CONFIG"); env != "" {
+		return absPath(env), "env"
+	}
+	if name := ActiveProfile(); name != DefaultProfile {
+		return ProfilePath(name), "profile"
 	}
-	return &cfg, nil
+	return filepath.Join(baseDir(), configFile), "default"
+}
+
+// Load reads the config from disk. Returns error if it doesn't exist.
+func Load() (*Config, error) {
+	return loadFile(FilePath())
+}
+
+func loadFile(path string) (*Config, error) {
+	return loadLayered(path, map[string]bool{})
 }
 
 // Exists returns true if the config file exists.
@@ -80,17 +181,44 @@ func Exists() bool {
 	return err == nil
 }
 
-// Save writes the config to disk, creating directories as needed.
+// Save writes the config to disk, creating directories as needed. The file
+// is replaced atomically, so readers see either the old or the new config.
+// An existing config is first copied to BackupPath so it can be restored.
 func Save(cfg *Config) error {
 	path := FilePath()
 	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
 		return err
 	}
-	data, err := yaml.Marshal(cfg)
+	data, err := marshalFile(path, cfg)
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(path, data, 0644)
+	// Back up before touching the original so a failed write still
+	// leaves the previous config recoverable.
+	if Exists() {
+		if err := copyFile(path, BackupPath()); err != nil {
+			return fmt.Errorf("failed to back up config: %w", err)
+		}
+	}
+	return writeFileAtomic(path, data, 0644)
+}
+
+// BackupPath returns the path of the single config backup kept by Save.
+func BackupPath() string {
+	return FilePath() + ".bak"
+}
+
+// RestoreBackup replaces the config with the backup written by the last Save.
+func RestoreBackup() error {
+	data, err := os.ReadFile(BackupPath())
+	if err != nil {
+		return fmt.Errorf("no backup found: %w", err)
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("backup is not a valid config: %w", err)
+	}
+	return writeFileAtomic(FilePath(), data, 0644)
 }
 
 // LoadOrCreate loads existing config or runs interactive prompts to create one.
@@ -114,114 +242,247 @@ func LoadOrCreate() (*Config, error) {
 
 // PromptForConfig runs interactive prompts. If existing is non-nil, its values are used as defaults.
 func PromptForConfig(existing *Config) (*Config, error) {
+	return PromptForConfigPartial(existing, false)
+}
+
+// PromptForConfigPartial is PromptForConfig, but with onlyEmpty set it skips
+// every field that already has a value in existing and only asks for the
+// empty ones, e.g. fields added since the config was written. Yes/no
+// settings always have a value, so they are never asked in that mode.
+func PromptForConfigPartial(existing *Config, onlyEmpty bool) (*Config, error) {
 	reader := bufio.NewReader(os.Stdin)
 	cfg := DefaultConfig()
 
 	if existing != nil {
 		*cfg = *existing
 	}
+	onlyEmpty = onlyEmpty && existing != nil
+
+	// ask reports whether a field holding current should be prompted for
+	ask := func(current string) bool {
+		return !onlyEmpty || current == ""
+	}
+	askBool := !onlyEmpty
 
 	var err error
 
-	cfg.Username, err = prompt(reader, "Username", cfg.Username, whoami())
-	if err != nil {
-		return nil, err
+	if ask(cfg.Username) {
+		cfg.Username, err = prompt(reader, "Username", cfg.Username, whoami())
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	cfg.Email, err = prompt(reader, "Email", cfg.Email, "")
-	if err != nil {
-		return nil, err
+	for ask(cfg.Email) {
+		cfg.Email, err = prompt(reader, "Email", cfg.Email, "")
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Email == "" || isValidEmail(cfg.Email) {
+			break
+		}
+		fmt.Println("    Invalid email. Please enter an address like user@example.com.")
+		cfg.Email = ""
 	}
 
-	cfg.GitName, err = prompt(reader, "Git display name", cfg.GitName, cfg.Username)
-	if err != nil {
-		return nil, err
+	if ask(cfg.GitName) {
+		cfg.GitName, err = prompt(reader, "Git display name", cfg.GitName, cfg.Username)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	cfg.GitEmail, err = prompt(reader, "Git email", cfg.GitEmail, cfg.Email)
-	if err != nil {
-		return nil, err
+	for ask(cfg.GitEmail) {
+		cfg.GitEmail, err = prompt(reader, "Git email", cfg.GitEmail, cfg.Email)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.GitEmail == "" || isValidEmail(cfg.GitEmail) {
+			break
+		}
+		fmt.Println("    Invalid email. Please enter an address like user@example.com.")
+		cfg.GitEmail = ""
 	}
 
-	cfg.GitHTTPS, err = promptBool(reader, "Use HTTPS for GitHub (instead of SSH)?", cfg.GitHTTPS)
-	if err != nil {
-		return nil, err
+	if askBool {
+		cfg.GitHTTPS, err = promptBool(reader, "Use HTTPS for GitHub (instead of SSH)?", cfg.GitHTTPS)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	for {
-		cfg.DefaultShell, err = prompt(reader, "Default shell (bash/zsh)", cfg.DefaultShell, "zsh")
+	for ask(cfg.DefaultShell) {
+		cfg.DefaultShell, err = prompt(reader, "Default shell (bash/zsh/fish)", cfg.DefaultShell, "zsh")
 		if err != nil {
 			return nil, err
 		}
 		if validShells[cfg.DefaultShell] {
 			break
 		}
-		fmt.Println("    Invalid shell. Please enter 'bash' or 'zsh'.")
+		fmt.Println("    Invalid shell. Please enter 'bash', 'zsh' or 'fish'.")
 	}
 
-	cfg.InstallPodman, err = promptBool(reader, "Install Podman (remote client)?", cfg.InstallPodman)
-	if err != nil {
-		return nil, err
+	if askBool {
+		cfg.InstallPodman, err = promptBool(reader, "Install Podman (remote client)?", cfg.InstallPodman)
+		if err != nil {
+			return nil, err
+		}
 	}
-
-	cfg.InstallBun, err = promptBool(reader, "Install Bun?", cfg.InstallBun)
-	if err != nil {
-		return nil, err
+	if cfg.InstallPodman {
+		if ask(cfg.PodmanWSLDistro) {
+			cfg.PodmanWSLDistro, err = prompt(reader, "Podman WSL distro", cfg.PodmanWSLDistro, "podman-machine")
+			if err != nil {
+				return nil, err
+			}
+		}
+		if ask(cfg.PodmanWSLHost) {
+			cfg.PodmanWSLHost, err = prompt(reader, "Podman WSL host", cfg.PodmanWSLHost, "localhost")
+			if err != nil {
+				return nil, err
+			}
+		}
+		if ask(cfg.PodmanWSLPort) {
+			cfg.PodmanWSLPort, err = prompt(reader, "Podman WSL port", cfg.PodmanWSLPort, "22")
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
 
-	cfg.InstallGo, err = promptBool(reader, "Install Go?", cfg.InstallGo)
-	if err != nil {
-		return nil, err
+	if askBool {
+		cfg.InstallBun, err = promptBool(reader, "Install Bun?", cfg.InstallBun)
+		if err != nil {
+			return nil, err
+		}
+
+		cfg.InstallGo, err = promptBool(reader, "Install Go?", cfg.InstallGo)
+		if err != nil {
+			return nil, err
+		}
 	}
-	if cfg.InstallGo {
+	if cfg.InstallGo && ask(cfg.GoVersion) {
 		cfg.GoVersion, err = prompt(reader, "Go version (or 'latest')", cfg.GoVersion, "latest")
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	cfg.InstallDotnet, err = promptBool(reader, "Install .NET SDK?", cfg.InstallDotnet)
-	if err != nil {
-		return nil, err
+	if askBool {
+		cfg.InstallDotnet, err = promptBool(reader, "Install .NET SDK?", cfg.InstallDotnet)
+		if err != nil {
+			return nil, err
+		}
 	}
-	if cfg.InstallDotnet {
+	if cfg.InstallDotnet && ask(cfg.DotnetVersion) {
 		cfg.DotnetVersion, err = prompt(reader, ".NET SDK version (or 'latest')", cfg.DotnetVersion, "latest")
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	cfg.InstallPython, err = promptBool(reader, "Install Python?", cfg.InstallPython)
-	if err != nil {
-		return nil, err
+	if askBool {
+		cfg.InstallPython, err = promptBool(reader, "Install Python?", cfg.InstallPython)
+		if err != nil {
+			return nil, err
+		}
 	}
-	if cfg.InstallPython {
+	if cfg.InstallPython && ask(cfg.PythonVersion) {
 		cfg.PythonVersion, err = prompt(reader, "Python version (or 'latest')", cfg.PythonVersion, "latest")
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	cfg.InstallK9s, err = promptBool(reader, "Install k9s (Kubernetes TUI)?", cfg.InstallK9s)
-	if err != nil {
-		return nil, err
+	if askBool {
+		cfg.InstallK9s, err = promptBool(reader, "Install k9s (Kubernetes TUI)?", cfg.InstallK9s)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	pkgs, err := prompt(reader, "Extra apt packages (comma-separated)", strings.Join(cfg.ExtraPackages, ", "), "ripgrep, fd-find, jq, htop")
-	if err != nil {
-		return nil, err
-	}
-	cfg.ExtraPackages = nil
-	for _, p := range strings.Split(pkgs, ",") {
-		p = strings.TrimSpace(p)
-		if p != "" {
-			cfg.ExtraPackages = append(cfg.ExtraPackages, p)
+	if ask(strings.Join(cfg.ExtraPackages, ", ")) {
+		pkgs, err := prompt(reader, "Extra apt packages (comma-separated)", strings.Join(cfg.ExtraPackages, ", "), "ripgrep, fd-find, jq, htop")
+		if err != nil {
+			return nil, err
+		}
+		cfg.ExtraPackages = nil
+		for _, p := range strings.Split(pkgs, ",") {
+			p = strings.TrimSpace(p)
+			if p != "" {
+				cfg.ExtraPackages = append(cfg.ExtraPackages, p)
+			}
 		}
 	}
 
 	return cfg, nil
 }
 
+// Validate checks that the config holds supported values.
+func (c *Config) Validate() error {
+	if !validShells[c.DefaultShell] {
+		return fmt.Errorf("default_shell must be bash, zsh or fish (got %q)", c.DefaultShell)
+	}
+	if c.Email != "" && !isValidEmail(c.Email) {
+		return fmt.Errorf("email %q is not a valid address", c.Email)
+	}
+	if c.GitEmail != "" && !isValidEmail(c.GitEmail) {
+		return fmt.Errorf("git_email %q is not a valid address", c.GitEmail)
+	}
+	if !validInstallMethods[c.AnsibleInstallMethod] {
+		return fmt.Errorf("ansible_install_method must be auto, apt or pipx (got %q)", c.AnsibleInstallMethod)
+	}
+	if c.Forks < 0 {
+		return fmt.Errorf("forks must be a positive number (got %d)", c.Forks)
+	}
+	if c.InstallTimeout != "" {
+		if d, err := time.ParseDuration(c.InstallTimeout); err != nil || d <= 0 {
+			return fmt.Errorf("install_timeout must be a positive duration like 10m (got %q)", c.InstallTimeout)
+		}
+	}
+	return nil
+}
+
+// InstallTimeoutDuration returns InstallTimeout parsed, or 0 if it is unset
+// or invalid so callers fall back to the default.
+func (c *Config) InstallTimeoutDuration() time.Duration {
+	d, err := time.ParseDuration(c.InstallTimeout)
+	if err != nil {
+		return 0
+	}
+	return d
+}
+
+// DryRunSkipTags returns the comma-separated role tags to pass as --skip-tags
+// for a run. Only dry runs skip CheckModeExcludedRoles.
+func (c *Config) DryRunSkipTags(dryRun bool) string {
+	if !dryRun {
+		return ""
+	}
+	return strings.Join(c.CheckModeExcludedRoles, ",")
+}
+
+// RunSkipTags adds DryRunSkipTags to the user's own --skip-tags value.
+func (c *Config) RunSkipTags(skipTags string, dryRun bool) string {
+	return strings.Trim(skipTags+","+c.DryRunSkipTags(dryRun), ",")
+}
+
+// HookEnv returns the extra vars as FLUX_<NAME>=value environment entries
+// for the post-run hook, e.g. FLUX_GIT_EMAIL. Lists are space-separated so
+// a shell can loop over them.
+func (c *Config) HookEnv() []string {
+	vars := c.ToExtraVars()
+	env := make([]string, 0, len(vars))
+	for k, v := range vars {
+		val := fmt.Sprint(v)
+		if list, ok := v.([]string); ok {
+			val = strings.Join(list, " ")
+		}
+		env = append(env, "FLUX_"+strings.ToUpper(k)+"="+val)
+	}
+	sort.Strings(env)
+	return env
+}
+
 // Marshal returns the YAML representation of the config.
 func (c *Config) Marshal() ([]byte, error) {
 	return yaml.Marshal(c)
@@ -236,19 +497,22 @@ func (c *Config) Marshal() ([]byte, error) {
 // resolving "latest" to a real version number.
 func (c *Config) ToExtraVars() map[string]interface{} {
 	vars := map[string]interface{}{
-		"username":       c.Username,
-		"email":          c.Email,
-		"git_name":       c.GitName,
-		"git_email":      c.GitEmail,
-		"git_https":      c.GitHTTPS,
-		"default_shell":  c.DefaultShell,
-		"install_podman": c.InstallPodman,
-		"install_bun":    c.InstallBun,
-		"install_go":     c.InstallGo,
-		"install_dotnet": c.InstallDotnet,
-		"install_python": c.InstallPython,
-		"install_k9s":    c.InstallK9s,
-		"extra_packages": c.ExtraPackages,
+		"username":          c.Username,
+		"email":             c.Email,
+		"git_name":          c.GitName,
+		"git_email":         c.GitEmail,
+		"git_https":         c.GitHTTPS,
+		"default_shell":     c.DefaultShell,
+		"install_podman":    c.InstallPodman,
+		"podman_wsl_distro": c.PodmanWSLDistro,
+		"podman_wsl_host":   c.PodmanWSLHost,
+		"podman_wsl_port":   c.PodmanWSLPort,
+		"install_bun":       c.InstallBun,
+		"install_go":        c.InstallGo,
+		"install_dotnet":    c.InstallDotnet,
+		"install_python":    c.InstallPython,
+		"install_k9s":       c.InstallK9s,
+		"extra_packages":    c.ExtraPackages,
 	}
 
 	// Only pass version extra-vars when a specific version is requested.
@@ -277,6 +541,22 @@ func AvailableRoles() []string {
 	return []string{"base", "git-config", "shell", "podman", "golang", "bun", "dotnet", "python", "k9s"}
 }
 
+// RoleDescriptions returns a one-line summary of each built-in role, keyed by
+// role tag. Roles not listed here have no description.
+func RoleDescriptions() map[string]string {
+	return map[string]string{
+		"base":       "Core apt packages (build-essential, curl, git, ...)",
+		"git-config": "~/.gitconfig with your name, email and HTTPS rewrite",
+		"shell":      "zsh, oh-my-zsh, plugins, starship and .zshrc",
+		"podman":     "Podman remote client and compose, pointed at the WSL machine",
+		"golang":     "Go toolchain in /usr/local/go",
+		"bun":        "Bun JavaScript runtime",
+		"dotnet":     ".NET SDK",
+		"python":     "Python from the deadsnakes PPA, set as the default python",
+		"k9s":        "k9s Kubernetes terminal UI",
+	}
+}
+
 // DiscoverRoles scans the ansible/roles/ directory and returns role names.
 // Falls back to the hardcoded list if the directory cannot be read.
 func DiscoverRoles(ansibleDir string) []string {
@@ -301,8 +581,47 @@ func DiscoverRoles(ansibleDir string) []string {
 	return roles
 }
 
+// ValidateTags returns an error naming any of the comma-separated tags that
+// isn't one of roles, listing the valid ones.
+func ValidateTags(tags string, roles []string) error {
+	known := make(map[string]bool, len(roles))
+	for _, r := range roles {
+		known[r] = true
+	}
+	var unknown []string
+	for _, t := range splitList(tags) {
+		if !known[t] {
+			unknown = append(unknown, t)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	return fmt.Errorf("unknown tag(s): %s (valid: %s)", strings.Join(unknown, ", "), strings.Join(roles, ", "))
+}
+
 // --- helpers ---
 
+// RunRoles returns the roles a `flux run` with these --tags and
+// --skip-tags runs: the tags themselves, or with no tags every role in
+// ansibleDir less the skipped ones.
+func RunRoles(ansibleDir, tags, skipTags string) []string {
+	if roles := splitList(tags); len(roles) > 0 {
+		return roles
+	}
+	skip := map[string]bool{}
+	for _, t := range splitList(skipTags) {
+		skip[t] = true
+	}
+	var roles []string
+	for _, r := range DiscoverRoles(ansibleDir) {
+		if !skip[r] {
+			roles = append(roles, r)
+		}
+	}
+	return roles
+}
+
 func prompt(reader *bufio.Reader, label, current, fallback string) (string, error) {
 	def := current
 	if def == "" {
@@ -324,21 +643,69 @@ func prompt(reader *bufio.Reader, label, current, fallback string) (string, erro
 	return line, nil
 }
 
+// promptBool asks a yes/no question, accepting anything ParseBoolStrict
+// does. Empty input keeps current; anything unrecognised asks again.
 func promptBool(reader *bufio.Reader, label string, current bool) (bool, error) {
 	def := "y"
 	if !current {
 		def = "n"
 	}
-	fmt.Printf("  %s [%s]: ", label, def)
-	line, err := reader.ReadString('\n')
+	for {
+		fmt.Printf("  %s [%s]: ", label, def)
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return false, err
+		}
+		if strings.TrimSpace(line) == "" {
+			return current, nil
+		}
+		if v, ok := ParseBoolStrict(line); ok {
+			return v, nil
+		}
+		fmt.Println("    Please answer y or n (yes/no, true/false, on/off and 1/0 work too).")
+	}
+}
+
+func copyFile(src, dst string) error {
+	data, err := os.ReadFile(src)
 	if err != nil {
-		return false, err
+		return err
 	}
-	line = strings.TrimSpace(strings.ToLower(line))
-	if line == "" {
-		return current, nil
+	return os.WriteFile(dst, data, 0644)
+}
+
+// writeFileAtomic writes data to a temp file beside path, syncs it and renames
+// it over path, so a crash mid-write never leaves a truncated file behind.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	// CreateTemp uses 0600; match what os.WriteFile would have created
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
 	}
-	return line == "y" || line == "yes", nil
+	return path
 }
 
 func whoami() string {
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// useTempConfig points the config (and everything under ~/.config/flux)
+// at a fresh temp directory for the length of the test.
+func useTempConfig(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("FLUX_CONFIG", "")
+	path := filepath.Join(home, "config.yaml")
+	SetFilePath(path)
+	t.Cleanup(func() { pathOverride = "" })
+	return path
+}
+
+func TestSaveLoadPodmanFields(t *testing.T) {
+	useTempConfig(t)
+
+	cfg := DefaultConfig()
+	cfg.Username = "jay"
+	cfg.InstallPodman = true
+	cfg.PodmanWSLDistro = "podman-dev"
+	cfg.PodmanWSLHost = "192.168.50.2"
+	cfg.PodmanWSLPort = "2222"
+	if err := Save(cfg); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	got, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got.PodmanWSLDistro != "podman-dev" || got.PodmanWSLHost != "192.168.50.2" || got.PodmanWSLPort != "2222" {
+		t.Errorf("podman fields = %q, %q, %q; want podman-dev, 192.168.50.2, 2222",
+			got.PodmanWSLDistro, got.PodmanWSLHost, got.PodmanWSLPort)
+	}
+
+	vars := got.ToExtraVars()
+	for key, want := range map[string]string{
+		"podman_wsl_distro": "podman-dev",
+		"podman_wsl_host":   "192.168.50.2",
+		"podman_wsl_port":   "2222",
+	} {
+		if vars[key] != want {
+			t.Errorf("extra var %s = %v, want %q", key, vars[key], want)
+		}
+	}
+}
+
+func TestDefaultConfigPodmanFields(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.PodmanWSLDistro != "podman-machine" || cfg.PodmanWSLHost != "localhost" || cfg.PodmanWSLPort != "22" {
+		t.Errorf("defaults = %q, %q, %q; want podman-machine, localhost, 22",
+			cfg.PodmanWSLDistro, cfg.PodmanWSLHost, cfg.PodmanWSLPort)
+	}
+}
+
+func TestFishShell(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Username = "jay"
+	if cfg.DefaultShell != "zsh" {
+		t.Errorf("default shell = %q, want zsh", cfg.DefaultShell)
+	}
+
+	cfg.DefaultShell = "fish"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate with fish: %v", err)
+	}
+	if got := cfg.ToExtraVars()["default_shell"]; got != "fish" {
+		t.Errorf("default_shell extra var = %v, want fish", got)
+	}
+
+	cfg.DefaultShell = "tcsh"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate accepted tcsh")
+	}
+}
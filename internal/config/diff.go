@@ -0,0 +1,96 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FieldDiff is a single config field whose value differs between two configs.
+type FieldDiff struct {
+	Key string
+	Old string
+	New string
+}
+
+// Diff returns the fields that differ between a and b, in declaration order.
+// Values are rendered the way SetField accepts them.
+func Diff(a, b *Config) []FieldDiff {
+	va := reflect.ValueOf(a).Elem()
+	vb := reflect.ValueOf(b).Elem()
+	t := va.Type()
+
+	var diffs []FieldDiff
+	for i := 0; i < t.NumField(); i++ {
+		key := yamlKey(t.Field(i))
+		if key == "" {
+			continue
+		}
+		oldVal, newVal := formatField(va.Field(i)), formatField(vb.Field(i))
+		if oldVal != newVal {
+			diffs = append(diffs, FieldDiff{Key: key, Old: oldVal, New: newVal})
+		}
+	}
+	return diffs
+}
+
+// formatField renders a config field as a string. Nil and empty lists both
+// render as "" so they compare equal.
+func formatField(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Bool:
+		return BoolStr(v.Bool())
+	case reflect.Slice:
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = fmt.Sprint(v.Index(i).Interface())
+		}
+		return strings.Join(items, ", ")
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+// NewOption is a config field the saved file doesn't mention yet, usually
+// because it was added in a later flux version.
+type NewOption struct {
+	Key     string
+	Default string
+}
+
+// NewOptions compares the fields of DefaultConfig with the keys present in
+// the saved config file and returns those missing from it, in declaration
+// order. Presence is what counts, not value: a field that's absent but
+// declared omitempty with an empty default is indistinguishable from one
+// the user left empty, so it isn't reported.
+func NewOptions() ([]NewOption, error) {
+	data, err := os.ReadFile(FilePath())
+	if err != nil {
+		return nil, err
+	}
+	var saved map[string]interface{}
+	if err := yaml.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", FilePath(), err)
+	}
+
+	def := reflect.ValueOf(DefaultConfig()).Elem()
+	t := def.Type()
+	var opts []NewOption
+	for i := 0; i < t.NumField(); i++ {
+		key := yamlKey(t.Field(i))
+		if key == "" {
+			continue
+		}
+		if _, ok := saved[key]; ok {
+			continue
+		}
+		if strings.Contains(t.Field(i).Tag.Get("yaml"), ",omitempty") && def.Field(i).IsZero() {
+			continue
+		}
+		opts = append(opts, NewOption{Key: key, Default: formatField(def.Field(i))})
+	}
+	return opts, nil
+}
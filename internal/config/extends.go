@@ -0,0 +1,106 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxExtendsDepth bounds how many files an extends chain may span.
+const maxExtendsDepth = 8
+
+// A config file may start from a shared base with `extends: <path>`. The base
+// is loaded first (following its own extends, if any) and every key the file
+// itself sets is applied on top, so for each field the file closest to the
+// one being loaded wins. Setting a key overrides the base even when the
+// value is false or empty; lists replace the base list rather than adding to
+// it. Relative paths are resolved against the directory of the file that
+// contains them.
+
+// loadLayered reads path and, if it extends another file, overlays it on
+// that base. visited holds the files already on the chain, to catch cycles.
+func loadLayered(path string, visited map[string]bool) (*Config, error) {
+	abs := absPath(path)
+	if visited[abs] {
+		return nil, fmt.Errorf("config extends cycle: %s is included twice", abs)
+	}
+	if len(visited) >= maxExtendsDepth {
+		return nil, fmt.Errorf("config extends chain is deeper than %d files at %s", maxExtendsDepth, abs)
+	}
+	visited[abs] = true
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var head struct {
+		Extends string `yaml:"extends"`
+	}
+	if err := yaml.Unmarshal(data, &head); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	cfg := &Config{}
+	if head.Extends != "" {
+		basePath := resolveExtends(path, head.Extends)
+		cfg, err = loadLayered(basePath, visited)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, fmt.Errorf("%s extends %s, which doesn't exist", path, basePath)
+			}
+			return nil, err
+		}
+	}
+	// Decoding into the base only replaces the keys this file sets
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	cfg.Extends = head.Extends
+	return cfg, nil
+}
+
+// resolveExtends returns the file ref points at, relative to the directory
+// of the config file from.
+func resolveExtends(from, ref string) string {
+	if rest, ok := strings.CutPrefix(ref, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	if filepath.IsAbs(ref) {
+		return ref
+	}
+	return filepath.Join(filepath.Dir(from), ref)
+}
+
+// marshalFile returns the YAML to write to path for cfg. A config that
+// extends a base only stores the fields that differ from it, so later
+// changes to the base still come through.
+func marshalFile(path string, cfg *Config) ([]byte, error) {
+	if cfg.Extends == "" {
+		return yaml.Marshal(cfg)
+	}
+	base, err := loadLayered(resolveExtends(path, cfg.Extends), map[string]bool{absPath(path): true})
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &yaml.Node{Kind: yaml.MappingNode}
+	for _, key := range FieldKeys() {
+		mine, _ := cfg.GetField(key)
+		theirs, _ := base.GetField(key)
+		if key != "extends" && mine == theirs {
+			continue
+		}
+		field, _ := fieldByTag(cfg, key)
+		var val yaml.Node
+		if err := val.Encode(field.Interface()); err != nil {
+			return nil, err
+		}
+		doc.Content = append(doc.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, &val)
+	}
+	return yaml.Marshal(doc)
+}
@@ -0,0 +1,155 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// SetField sets the field whose yaml tag matches key, parsing value according
+// to the field's type. List fields accept comma-separated input.
+func (c *Config) SetField(key, value string) error {
+	field, ok := fieldByTag(c, key)
+	if !ok {
+		return fmt.Errorf("unknown config key %q", key)
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(strings.TrimSpace(value))
+	case reflect.Bool:
+		b, err := parseBoolValue(value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		field.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("%s: invalid number %q", key, value)
+		}
+		field.SetInt(int64(n))
+	case reflect.Slice:
+		field.Set(reflect.ValueOf(splitList(value)))
+	default:
+		return fmt.Errorf("%s: unsupported field type %s", key, field.Kind())
+	}
+	return nil
+}
+
+// SetValues applies key=value pairs to the saved config (defaults if there
+// is none yet), validates the result and saves it. Every pair is tried so
+// all mistakes are reported at once; if any fails nothing is saved.
+func SetValues(pairs []string) error {
+	cfg, err := Load()
+	if err != nil {
+		if Exists() {
+			return fmt.Errorf("config file is corrupt: %w", err)
+		}
+		cfg = DefaultConfig()
+	}
+	var errs []error
+	for _, pair := range pairs {
+		key, val, ok := strings.Cut(pair, "=")
+		if !ok {
+			errs = append(errs, fmt.Errorf("invalid pair %q (expected key=value)", pair))
+			continue
+		}
+		if err := cfg.SetField(key, val); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 0 {
+		if err := cfg.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	return Save(cfg)
+}
+
+// GetField returns the value of the field whose yaml tag matches key, in the
+// form SetField accepts. List fields are comma-separated.
+func (c *Config) GetField(key string) (string, error) {
+	field, ok := fieldByTag(c, key)
+	if !ok {
+		return "", fmt.Errorf("unknown config key %q", key)
+	}
+	return formatField(field), nil
+}
+
+// FieldKeys returns the yaml keys of all config fields in declaration order.
+func FieldKeys() []string {
+	t := reflect.TypeOf(Config{})
+	keys := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		if key := yamlKey(t.Field(i)); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// IsBoolField reports whether key names a boolean config field.
+func IsBoolField(key string) bool {
+	field, ok := fieldByTag(&Config{}, key)
+	return ok && field.Kind() == reflect.Bool
+}
+
+// fieldByTag returns the settable struct field whose yaml tag is key.
+func fieldByTag(c *Config, key string) (reflect.Value, bool) {
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if yamlKey(t.Field(i)) == key {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+func yamlKey(f reflect.StructField) string {
+	tag := f.Tag.Get("yaml")
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+func parseBoolValue(s string) (bool, error) {
+	if v, ok := ParseBoolStrict(s); ok {
+		return v, nil
+	}
+	return false, fmt.Errorf("invalid boolean %q (use true/false)", s)
+}
+
+// ParseBoolStrict parses a yes/no answer the way config values are read:
+// true/yes/y/t/1/on and false/no/n/f/0/off, ignoring case and surrounding
+// space. ok is false for anything else, so callers can ask again instead of
+// guessing. The config prompts and the TUI share it so they agree.
+func ParseBoolStrict(s string) (v, ok bool) {
+	switch strings.TrimSpace(strings.ToLower(s)) {
+	case "true", "yes", "y", "1", "on", "t":
+		return true, true
+	case "false", "no", "n", "0", "off", "f":
+		return false, true
+	}
+	return false, false
+}
+
+// splitList splits comma-separated input, dropping empty entries.
+func splitList(s string) []string {
+	var out []string
+	for _, p := range strings.Split(s, ",") {
+		p = strings.TrimSpace(p)
+		if p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSetValuesBatch(t *testing.T) {
+	useTempConfig(t)
+	cfg := DefaultConfig()
+	cfg.Username = "jay"
+	if err := Save(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	err := SetValues([]string{"default_shell=fish", "install_go=off", "forks=10", "extra_packages=jq, htop"})
+	if err != nil {
+		t.Fatalf("SetValues: %v", err)
+	}
+
+	got, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.DefaultShell != "fish" || got.InstallGo || got.Forks != 10 || got.Username != "jay" {
+		t.Errorf("got shell=%q install_go=%v forks=%d username=%q", got.DefaultShell, got.InstallGo, got.Forks, got.Username)
+	}
+	if len(got.ExtraPackages) != 2 || got.ExtraPackages[0] != "jq" || got.ExtraPackages[1] != "htop" {
+		t.Errorf("extra_packages = %q, want [jq htop]", got.ExtraPackages)
+	}
+}
+
+func TestSetValuesAtomicFailure(t *testing.T) {
+	tests := []struct {
+		name  string
+		pairs []string
+	}{
+		{"unknown key", []string{"default_shell=fish", "no_such_key=1"}},
+		{"bad bool", []string{"default_shell=fish", "install_go=maybe"}},
+		{"bad number", []string{"default_shell=fish", "forks=many"}},
+		{"missing =", []string{"default_shell=fish", "install_go"}},
+		{"fails validation", []string{"default_shell=fish", "forks=-1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := useTempConfig(t)
+			cfg := DefaultConfig()
+			cfg.Username = "jay"
+			if err := Save(cfg); err != nil {
+				t.Fatal(err)
+			}
+			before, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := SetValues(tt.pairs); err == nil {
+				t.Fatal("SetValues succeeded, want an error")
+			}
+
+			after, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(after) != string(before) {
+				t.Errorf("config changed although the batch failed:\n%s", after)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// LockedError is returned by Lock when another flux process holds the lock.
+type LockedError struct {
+	PID int // 0 when the holder couldn't be identified
+}
+
+func (e *LockedError) Error() string {
+	if e.PID == 0 {
+		return "another flux operation is in progress"
+	}
+	return fmt.Sprintf("another flux operation is in progress (pid %d)", e.PID)
+}
+
+// LockPath returns the location of the lock file. Like the state file it
+// lives in ~/.config/flux whatever config file is in use, since two runs
+// fight over apt no matter which config they were started with.
+func LockPath() string {
+	return filepath.Join(baseDir(), "flux.lock")
+}
+
+// Lock takes the lock held for the length of a playbook run or update, so a
+// second flux doesn't run ansible-playbook or apt alongside the first. The
+// file records the holder's pid for the error message. The lock is released
+// by calling unlock, or by the kernel if flux dies, so a crashed run never
+// leaves it stuck; where file locks aren't available a pid that is no
+// longer running counts as stale.
+func Lock() (unlock func(), err error) {
+	path := LockPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	held, err := tryLock(f)
+	if err == nil && held {
+		pid := lockHolder(f)
+		switch {
+		case pid != 0 && pidAlive(pid):
+			err = &LockedError{PID: pid}
+		case fileLocking || pid == 0:
+			err = &LockedError{}
+		}
+		// Otherwise the previous holder died without cleaning up
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	f.Truncate(0)
+	f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	return func() {
+		f.Truncate(0)
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
+
+// lockHolder returns the pid recorded in the lock file, or 0.
+func lockHolder(f *os.File) int {
+	buf := make([]byte, 32)
+	n, _ := f.ReadAt(buf, 0)
+	pid, err := strconv.Atoi(strings.TrimSpace(string(buf[:n])))
+	if err != nil {
+		return 0
+	}
+	return pid
+}
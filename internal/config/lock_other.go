@@ -0,0 +1,25 @@
+//go:build !linux && !darwin
+
+package config
+
+import "os"
+
+// fileLocking is false here: the lock is just the pid in the file, which is
+// stale once that process has gone.
+const fileLocking = false
+
+// tryLock reports the lock as held whenever the file names another process;
+// Lock then checks whether that process is still running.
+func tryLock(f *os.File) (held bool, err error) {
+	pid := lockHolder(f)
+	return pid != 0 && pid != os.Getpid(), nil
+}
+
+func unlockFile(f *os.File) {}
+
+// pidAlive relies on os.FindProcess, which fails for exited processes on
+// Windows but not on every platform.
+func pidAlive(pid int) bool {
+	_, err := os.FindProcess(pid)
+	return err == nil
+}
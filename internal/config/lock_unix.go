@@ -0,0 +1,34 @@
+//go:build linux || darwin
+
+package config
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// fileLocking reports whether tryLock really locks; with flock the kernel
+// drops a dead process's lock, so a held lock is never stale.
+const fileLocking = true
+
+// tryLock takes an exclusive flock on f without waiting. held is true when
+// another process has it.
+func tryLock(f *os.File) (held bool, err error) {
+	err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return true, nil
+	}
+	return false, err
+}
+
+func unlockFile(f *os.File) {
+	syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
+
+// pidAlive reports whether a process with this pid exists. EPERM means it
+// does but belongs to someone else.
+func pidAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// roleMemory is the memory, in MiB, a role needs available to finish
+// without the out-of-memory killer stepping in, keyed by role tag. Roles
+// that aren't listed get by on what the base system leaves free; enabled
+// skips a role its install_* option turns off.
+var roleMemory = map[string]struct {
+	mib     int
+	enabled func(c *Config) bool
+}{
+	"dotnet": {2048, func(c *Config) bool { return c.InstallDotnet }},
+	"golang": {1024, func(c *Config) bool { return c.InstallGo }},
+}
+
+// MemoryCheck compares the memory available now with what the hungriest of
+// roles needs; they run one after another, so only the largest need
+// counts. It returns a warning when there isn't enough, and nil otherwise
+// or when the available memory can't be read (e.g. outside Linux).
+func MemoryCheck(cfg *Config, roles []string) *Warning {
+	need, hungriest := 0, ""
+	for _, r := range roles {
+		if req, ok := roleMemory[r]; ok && req.enabled(cfg) && req.mib > need {
+			need, hungriest = req.mib, r
+		}
+	}
+	if need == 0 {
+		return nil
+	}
+	if err := checkMemory(need); err != nil {
+		if _, ok := err.(*lowMemoryError); ok {
+			return &Warning{"memory", fmt.Sprintf("the %s role %v", hungriest, err)}
+		}
+	}
+	return nil
+}
+
+// lowMemoryError reports that less memory is available than required.
+type lowMemoryError struct {
+	required, available int // MiB
+}
+
+func (e *lowMemoryError) Error() string {
+	return fmt.Sprintf("needs about %d MB of free memory but only %d MB is available", e.required, e.available)
+}
+
+// checkMemory returns a *lowMemoryError if less than required MiB of memory
+// is available according to /proc/meminfo.
+func checkMemory(required int) error {
+	avail, err := availableMemory()
+	if err != nil {
+		return err
+	}
+	if avail < required {
+		return &lowMemoryError{required, avail}
+	}
+	return nil
+}
+
+// availableMemory returns MemAvailable from /proc/meminfo in MiB.
+func availableMemory() (int, error) {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		rest, ok := strings.CutPrefix(scanner.Text(), "MemAvailable:")
+		if !ok {
+			continue
+		}
+		kb, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(rest), " kB"))
+		if err != nil {
+			return 0, fmt.Errorf("unexpected MemAvailable line in /proc/meminfo: %q", scanner.Text())
+		}
+		return kb / 1024, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("no MemAvailable in /proc/meminfo")
+}
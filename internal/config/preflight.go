@@ -0,0 +1,114 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Warning is a problem PreflightCheck expects to make a run fail part way.
+type Warning struct {
+	Check   string // "disk", "memory" or "network"
+	Message string
+}
+
+// preflightTimeout bounds each connectivity probe.
+const preflightTimeout = 3 * time.Second
+
+// preflightHosts lists the download hosts the roles fetch from, each with
+// the condition under which a run needs it.
+var preflightHosts = []struct {
+	host    string
+	enabled func(c *Config) bool
+}{
+	{"github.com", func(*Config) bool { return true }},
+	{"raw.githubusercontent.com", func(*Config) bool { return true }},
+	{"starship.rs", func(*Config) bool { return true }},
+	{"go.dev", func(c *Config) bool { return c.InstallGo }},
+	{"dl.google.com", func(c *Config) bool { return c.InstallGo }},
+	{"dotnetcli.blob.core.windows.net", func(c *Config) bool { return c.InstallDotnet }},
+	{"packages.microsoft.com", func(c *Config) bool { return c.InstallDotnet }},
+	{"endoflife.date", func(c *Config) bool { return c.InstallPython }},
+	{"bun.sh", func(c *Config) bool { return c.InstallBun }},
+	{"api.github.com", func(c *Config) bool { return c.InstallPodman || c.InstallK9s }},
+}
+
+// gib is one gibibyte.
+const gib = 1 << 30
+
+// EstimatedDiskNeed is a rough, generous estimate of the disk space a full
+// run takes with c's install_* options: 1 GB for apt updates and the base
+// and shell roles, plus each enabled toolchain and 50 MB per extra package.
+func (c *Config) EstimatedDiskNeed() uint64 {
+	const mib = 1 << 20
+	need := uint64(gib)
+	for _, t := range []struct {
+		on   bool
+		size uint64
+	}{
+		{c.InstallGo, 500 * mib},
+		{c.InstallDotnet, 1536 * mib},
+		{c.InstallPython, 400 * mib},
+		{c.InstallBun, 150 * mib},
+		{c.InstallPodman, 300 * mib},
+		{c.InstallK9s, 150 * mib},
+	} {
+		if t.on {
+			need += t.size
+		}
+	}
+	return need + uint64(len(c.ExtraPackages))*50*mib
+}
+
+// PreflightCheck estimates the disk space the enabled install_* options
+// need, compares it with what is free in the home filesystem, and checks
+// that their download hosts are reachable on port 443. offline runs
+// download nothing, so their hosts aren't probed. It returns one warning per
+// problem found; none means the run looks good to go.
+func PreflightCheck(cfg *Config, offline bool) []Warning {
+	var warnings []Warning
+
+	need := cfg.EstimatedDiskNeed()
+	var hosts []string
+	for _, h := range preflightHosts {
+		if !offline && h.enabled(cfg) {
+			hosts = append(hosts, h.host)
+		}
+	}
+
+	home, _ := os.UserHomeDir()
+	if free, err := freeBytes(home); err == nil && free < need {
+		warnings = append(warnings, Warning{"disk", fmt.Sprintf(
+			"about %.1f GB needed but only %.1f GB free in %s", float64(need)/gib, float64(free)/gib, home)})
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, host := range hosts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, "443"), preflightTimeout)
+			if err != nil {
+				mu.Lock()
+				warnings = append(warnings, Warning{"network", fmt.Sprintf("can't reach %s: %v", host, err)})
+				mu.Unlock()
+				return
+			}
+			conn.Close()
+		}()
+	}
+	wg.Wait()
+
+	// Probes finish in any order; keep the report stable
+	sort.SliceStable(warnings, func(i, j int) bool {
+		if warnings[i].Check != warnings[j].Check {
+			return warnings[i].Check == "disk"
+		}
+		return warnings[i].Message < warnings[j].Message
+	})
+	return warnings
+}
@@ -0,0 +1,27 @@
+package config
+
+import "testing"
+
+func TestPreflightCheckOffline(t *testing.T) {
+	prev := preflightHosts
+	t.Cleanup(func() { preflightHosts = prev })
+	// One host that always needs probing, and never answers
+	preflightHosts = append(preflightHosts[:0:0], prev[0])
+	preflightHosts[0].host = "flux-preflight.invalid"
+
+	networkWarnings := func(offline bool) int {
+		n := 0
+		for _, w := range PreflightCheck(DefaultConfig(), offline) {
+			if w.Check == "network" {
+				n++
+			}
+		}
+		return n
+	}
+	if n := networkWarnings(false); n != 1 {
+		t.Errorf("online: %d network warnings, want 1 for the unreachable host", n)
+	}
+	if n := networkWarnings(true); n != 0 {
+		t.Errorf("offline: %d network warnings, want the hosts not probed", n)
+	}
+}
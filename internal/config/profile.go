@@ -0,0 +1,127 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	profilesDir = "profiles"
+	activeFile  = "active-profile"
+
+	// DefaultProfile names the plain config.yaml, used when no profile is active.
+	DefaultProfile = "default"
+)
+
+// baseDir returns ~/.config/flux, which holds profiles and the active-profile
+// state file regardless of any --config or FLUX_CONFIG override.
+func baseDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, configDir)
+}
+
+// ProfilePath returns the file backing the named profile. DefaultProfile
+// maps to the plain config.yaml.
+func ProfilePath(name string) string {
+	if name == DefaultProfile {
+		return filepath.Join(baseDir(), configFile)
+	}
+	return filepath.Join(baseDir(), profilesDir, name+".yaml")
+}
+
+// validateProfileName rejects names that would escape the profiles directory.
+func validateProfileName(name string) error {
+	if name == "" {
+		return fmt.Errorf("invalid profile name %q", name)
+	}
+	if strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return fmt.Errorf("invalid profile name %q", name)
+	}
+	return nil
+}
+
+// LoadProfile reads the named profile.
+func LoadProfile(name string) (*Config, error) {
+	if err := validateProfileName(name); err != nil {
+		return nil, err
+	}
+	cfg, err := loadFile(ProfilePath(name))
+	if err != nil {
+		return nil, fmt.Errorf("profile %s: %w", name, err)
+	}
+	return cfg, nil
+}
+
+// SaveProfile writes cfg as the named profile, creating it if needed.
+func SaveProfile(name string, cfg *Config) error {
+	if err := validateProfileName(name); err != nil {
+		return err
+	}
+	path := ProfilePath(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := marshalFile(path, cfg)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, 0644)
+}
+
+// ListProfiles returns the names of all saved profiles, sorted.
+// The default profile is not included.
+func ListProfiles() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(baseDir(), profilesDir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if name, ok := strings.CutSuffix(e.Name(), ".yaml"); ok && !e.IsDir() && name != DefaultProfile {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// ActiveProfile returns the profile selected by UseProfile, or DefaultProfile.
+func ActiveProfile() string {
+	data, err := os.ReadFile(filepath.Join(baseDir(), activeFile))
+	if err != nil {
+		return DefaultProfile
+	}
+	name := strings.TrimSpace(string(data))
+	if name == "" || validateProfileName(name) != nil {
+		return DefaultProfile
+	}
+	return name
+}
+
+// UseProfile makes name the active profile for future runs. DefaultProfile
+// switches back to config.yaml. Other profiles must already exist.
+func UseProfile(name string) error {
+	state := filepath.Join(baseDir(), activeFile)
+	if name == DefaultProfile {
+		if err := os.Remove(state); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if err := validateProfileName(name); err != nil {
+		return err
+	}
+	if _, err := os.Stat(ProfilePath(name)); err != nil {
+		return fmt.Errorf("profile %q not found", name)
+	}
+	if err := os.MkdirAll(baseDir(), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(state, []byte(name+"\n"), 0644)
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// State is UI state remembered between runs. It is kept apart from Config
+// because it isn't a user setting and isn't passed to Ansible.
+type State struct {
+	// Roles maps each role name to whether it was selected in the last run.
+	// Roles missing from the map were added since and default to selected.
+	Roles map[string]bool `yaml:"roles,omitempty"`
+	// FailedRoles are the roles the last failed apply left undone, for
+	// flux run --retry-failed.
+	FailedRoles []string `yaml:"failed_roles,omitempty"`
+	// LastRun summarises the last run that finished, for
+	// flux run --since-last.
+	LastRun *LastRun `yaml:"last_run,omitempty"`
+}
+
+// LastRun is the summary of a finished run kept in the state file.
+type LastRun struct {
+	Time   time.Time `yaml:"time"`
+	DryRun bool      `yaml:"dry_run,omitempty"`
+	// PLAY RECAP totals across all hosts
+	Ok      int `yaml:"ok"`
+	Changed int `yaml:"changed"`
+	Failed  int `yaml:"failed"`
+	// ChangedRoles are the roles with a task that changed (or in a dry
+	// run, would have changed).
+	ChangedRoles []string `yaml:"changed_roles,omitempty"`
+}
+
+// StatePath returns the location of the state file.
+func StatePath() string {
+	return filepath.Join(baseDir(), "state.yaml")
+}
+
+// LogDir returns the directory holding flux's own log files.
+func LogDir() string {
+	return filepath.Join(baseDir(), "logs")
+}
+
+// LoadState reads the state file. A missing file yields an empty State.
+func LoadState() (*State, error) {
+	data, err := os.ReadFile(StatePath())
+	if os.IsNotExist(err) {
+		return &State{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var st State
+	if err := yaml.Unmarshal(data, &st); err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+// SaveState writes the state file, creating directories as needed.
+func SaveState(st *State) error {
+	path := StatePath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(st)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, 0644)
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadVarFile reads a YAML or JSON file of extra variables. JSON is valid
+// YAML, so a single decoder handles both formats. The top level must be a
+// mapping of variable names to values.
+func LoadVarFile(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid var file %s: %w", path, err)
+	}
+	switch vars := raw.(type) {
+	case nil:
+		return map[string]interface{}{}, nil
+	case map[string]interface{}:
+		return vars, nil
+	case []interface{}:
+		return nil, fmt.Errorf("invalid var file %s: top level is a list, expected a mapping of variable names to values", path)
+	default:
+		return nil, fmt.Errorf("invalid var file %s: top level is a scalar (%v), expected a mapping of variable names to values", path, vars)
+	}
+}
+
+// ExtraVarsPath returns the location of the optional extra-vars file that is
+// merged into every run, ~/.config/flux/extra-vars.yaml.
+func ExtraVarsPath() string {
+	return filepath.Join(baseDir(), "extra-vars.yaml")
+}
+
+// LoadExtraVars reads the extra-vars file at path, or ExtraVarsPath if path
+// is empty. A missing default file is not an error and yields nil.
+func LoadExtraVars(path string) (map[string]interface{}, error) {
+	if path == "" {
+		path = ExtraVarsPath()
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return nil, nil
+		}
+	}
+	return LoadVarFile(path)
+}
+
+// ParseSetVars converts key=value pairs (as given to --set) into a map.
+// Values are decoded as YAML scalars so "true" becomes a bool and "3" an int.
+func ParseSetVars(pairs []string) (map[string]interface{}, error) {
+	vars := map[string]interface{}{}
+	for _, pair := range pairs {
+		key, raw, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --set value %q (expected key=value)", pair)
+		}
+		var val interface{}
+		if err := yaml.Unmarshal([]byte(raw), &val); err != nil || val == nil {
+			val = raw
+		}
+		vars[key] = val
+	}
+	return vars, nil
+}
+
+// MergeVars merges extra-var maps at the top level. Later maps take
+// precedence over earlier ones; nil maps are skipped.
+func MergeVars(layers ...map[string]interface{}) map[string]interface{} {
+	merged := map[string]interface{}{}
+	for _, layer := range layers {
+		for k, v := range layer {
+			merged[k] = v
+		}
+	}
+	return merged
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTemp writes content to a file named name in a temp dir.
+func writeTemp(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestMergeVarsPrecedence(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Username = "from-config"
+	cfg.DefaultShell = "zsh"
+	cfg.PodmanWSLPort = "22"
+
+	fileVars, err := LoadVarFile(writeTemp(t, "vars.yaml", "default_shell: fish\npodman_wsl_port: \"2222\"\nextra_only: 1\n"))
+	if err != nil {
+		t.Fatalf("LoadVarFile: %v", err)
+	}
+	setVars, err := ParseSetVars([]string{"podman_wsl_port=2200", "debug=true"})
+	if err != nil {
+		t.Fatalf("ParseSetVars: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		layers []map[string]interface{}
+		key    string
+		want   interface{}
+	}{
+		{"config only", []map[string]interface{}{cfg.ToExtraVars()}, "default_shell", "zsh"},
+		{"var file over config", []map[string]interface{}{cfg.ToExtraVars(), fileVars}, "default_shell", "fish"},
+		{"config kept when file is silent", []map[string]interface{}{cfg.ToExtraVars(), fileVars, setVars}, "username", "from-config"},
+		{"file adds new keys", []map[string]interface{}{cfg.ToExtraVars(), fileVars}, "extra_only", 1},
+		{"--set over var file", []map[string]interface{}{cfg.ToExtraVars(), fileVars, setVars}, "podman_wsl_port", 2200},
+		{"--set values are typed", []map[string]interface{}{cfg.ToExtraVars(), fileVars, setVars}, "debug", true},
+		{"nil layers are skipped", []map[string]interface{}{cfg.ToExtraVars(), nil, setVars}, "default_shell", "zsh"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MergeVars(tt.layers...)[tt.key]; got != tt.want {
+				t.Errorf("%s = %#v, want %#v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadVarFileRejectsInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"malformed yaml", "default_shell: [fish\n"},
+		{"top-level list", "- a\n- b\n"},
+		{"top-level scalar", "just a string\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadVarFile(writeTemp(t, "vars.yaml", tt.content)); err == nil {
+				t.Error("LoadVarFile succeeded, want an error")
+			}
+		})
+	}
+
+	if _, err := LoadVarFile(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("LoadVarFile of a missing file succeeded, want an error")
+	}
+}
+
+func TestLoadVarFileJSON(t *testing.T) {
+	vars, err := LoadVarFile(writeTemp(t, "vars.json", `{"default_shell": "bash", "n": 3}`))
+	if err != nil {
+		t.Fatalf("LoadVarFile: %v", err)
+	}
+	if vars["default_shell"] != "bash" || vars["n"] != 3 {
+		t.Errorf("vars = %v", vars)
+	}
+}
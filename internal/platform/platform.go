@@ -0,0 +1,100 @@
+package platform
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// IsWSL reports whether flux is running inside Windows Subsystem for Linux.
+func IsWSL() bool {
+	if os.Getenv("WSL_DISTRO_NAME") != "" {
+		return true
+	}
+	data, err := os.ReadFile("/proc/version")
+	if err != nil {
+		return false
+	}
+	v := strings.ToLower(string(data))
+	return strings.Contains(v, "microsoft") || strings.Contains(v, "wsl")
+}
+
+// PackageManager returns the name of the first system package manager found
+// on PATH, or "" if none is recognised.
+func PackageManager() string {
+	for _, pm := range []string{"apt-get", "dnf", "yum", "pacman", "zypper", "apk"} {
+		if _, err := exec.LookPath(pm); err == nil {
+			return pm
+		}
+	}
+	return ""
+}
+
+// SudoMode describes how flux escalates privileges: "root" when already
+// running as uid 0, "sudo" when sudo is available, "none" otherwise.
+func SudoMode() string {
+	if os.Getuid() == 0 {
+		return "root"
+	}
+	if _, err := exec.LookPath("sudo"); err == nil {
+		return "sudo"
+	}
+	return "none"
+}
+
+// PasswordlessSudo reports whether sudo works without a password here
+// (NOPASSWD in sudoers). Cached credentials are ignored, so a recent
+// password prompt doesn't count.
+func PasswordlessSudo() bool {
+	if _, err := exec.LookPath("sudo"); err != nil {
+		return false
+	}
+	return exec.Command("sudo", "-k", "-n", "true").Run() == nil
+}
+
+// DistroID returns the ID field of /etc/os-release (e.g. "ubuntu", "debian"),
+// or "" if it can't be read.
+func DistroID() string {
+	data, err := os.ReadFile("/etc/os-release")
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if v, ok := strings.CutPrefix(line, "ID="); ok {
+			return strings.ToLower(strings.Trim(v, `"'`))
+		}
+	}
+	return ""
+}
+
+// MissingAptPackages returns the packages in pkgs that apt can't install,
+// going by `apt-cache policy`. It relies on an up to date apt cache, so a
+// package added since the last `apt-get update` may be reported as missing.
+func MissingAptPackages(pkgs []string) ([]string, error) {
+	if _, err := exec.LookPath("apt-cache"); err != nil {
+		return nil, fmt.Errorf("apt-cache not found; package checks need apt")
+	}
+	var missing []string
+	for _, pkg := range pkgs {
+		out, err := exec.Command("apt-cache", "policy", pkg).Output()
+		if err != nil {
+			return nil, fmt.Errorf("apt-cache policy %s: %w", pkg, err)
+		}
+		if !hasAptCandidate(string(out)) {
+			missing = append(missing, pkg)
+		}
+	}
+	return missing, nil
+}
+
+// hasAptCandidate reports whether apt-cache policy output names an
+// installable version. Unknown packages produce no output at all.
+func hasAptCandidate(policy string) bool {
+	for _, line := range strings.Split(policy, "\n") {
+		if v, ok := strings.CutPrefix(strings.TrimSpace(line), "Candidate:"); ok {
+			return strings.TrimSpace(v) != "(none)"
+		}
+	}
+	return false
+}
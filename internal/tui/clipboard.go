@@ -0,0 +1,35 @@
+package tui
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// clipboardCommands are tried in order by copyToClipboard. clip.exe comes
+// first because under WSL it reaches the Windows clipboard even when no
+// Linux clipboard is running.
+var clipboardCommands = [][]string{
+	{"clip.exe"},
+	{"wl-copy"},
+	{"xclip", "-selection", "clipboard"},
+	{"xsel", "--clipboard", "--input"},
+}
+
+// copyToClipboard puts text on the system clipboard using the first
+// clipboard tool found on PATH.
+func copyToClipboard(text string) error {
+	for _, c := range clipboardCommands {
+		path, err := exec.LookPath(c[0])
+		if err != nil {
+			continue
+		}
+		cmd := exec.Command(path, c[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("%s failed: %w %s", c[0], err, strings.TrimSpace(string(out)))
+		}
+		return nil
+	}
+	return fmt.Errorf("no clipboard tool found (tried clip.exe, wl-copy, xclip, xsel)")
+}
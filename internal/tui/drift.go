@@ -0,0 +1,176 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/jaydubyaeey/flux/internal/ansible"
+	"github.com/jaydubyaeey/flux/internal/config"
+)
+
+// Since Last Run does a dry run and compares the roles that would change
+// with the ones that changed in the run recorded before it, so a weekly
+// check only points out what is new rather than the whole diff.
+
+// rememberRun records a finished run as the state's LastRun. Runs without a
+// recap (e.g. ones that stopped before the first play) aren't recorded.
+// Failing to write the state only costs convenience.
+func rememberRun(recap ansible.Recap, events []ansible.TaskEvent, dryRun bool) {
+	if len(recap.Hosts) == 0 {
+		return
+	}
+	st, err := config.LoadState()
+	if err != nil {
+		st = &config.State{}
+	}
+	total := recap.Total()
+	st.LastRun = &config.LastRun{
+		Time:         time.Now(),
+		DryRun:       dryRun,
+		Ok:           total.Ok,
+		Changed:      total.Changed,
+		Failed:       total.Failed,
+		ChangedRoles: ansible.ChangedRoles(events),
+	}
+	_ = config.SaveState(st)
+}
+
+// rememberCLIRun is rememberRun for `flux run`, reading the recap and task
+// results from the captured output.
+func rememberCLIRun(output string, dryRun bool) {
+	rememberRun(ansible.ParseRecap(output), outputEvents(output), dryRun)
+}
+
+// loadLastRun returns the recorded last run, or nil if there is none.
+func loadLastRun() *config.LastRun {
+	st, err := config.LoadState()
+	if err != nil {
+		return nil
+	}
+	return st.LastRun
+}
+
+// compareRuns splits the roles that would change now into those that
+// drifted since last (they didn't change then) and those still changing as
+// they did last time. With no last run everything counts as drifted.
+func compareRuns(last *config.LastRun, changed []string) (drifted, still []string) {
+	before := map[string]bool{}
+	if last != nil {
+		for _, r := range last.ChangedRoles {
+			before[r] = true
+		}
+	}
+	for _, r := range changed {
+		if before[r] {
+			still = append(still, r)
+		} else {
+			drifted = append(drifted, r)
+		}
+	}
+	return drifted, still
+}
+
+// lastRunLabel describes when and how the last run happened.
+func lastRunLabel(last *config.LastRun) string {
+	kind := "apply"
+	if last.DryRun {
+		kind = "dry run"
+	}
+	return fmt.Sprintf("%s (%s)", last.Time.Local().Format("2006-01-02 15:04"), kind)
+}
+
+// sinceLastLines is the report `flux run --since-last` prints.
+func sinceLastLines(last *config.LastRun, drifted, still []string) []string {
+	var lines []string
+	switch {
+	case last == nil:
+		lines = append(lines, "No earlier run recorded; this dry run is the baseline from now on.")
+		if len(drifted) > 0 {
+			lines = append(lines, "Would change: "+strings.Join(drifted, ", "))
+		}
+		return lines
+	case len(drifted) == 0 && len(still) == 0:
+		return []string{"✓ No drift since " + lastRunLabel(last)}
+	case len(drifted) == 0:
+		lines = append(lines, "✓ No new drift since "+lastRunLabel(last))
+	default:
+		lines = append(lines, "Drifted since "+lastRunLabel(last)+": "+strings.Join(drifted, ", "))
+	}
+	if len(still) > 0 {
+		lines = append(lines, "Still changing, as last time: "+strings.Join(still, ", "))
+	}
+	return lines
+}
+
+// sinceLastCode prints the --since-last report for a dry run's output,
+// compared with last, and returns ExitDrift if any role drifted.
+func sinceLastCode(last *config.LastRun, output string) int {
+	drifted, still := compareRuns(last, ansible.ChangedRoles(outputEvents(output)))
+	fmt.Println()
+	for _, l := range sinceLastLines(last, drifted, still) {
+		fmt.Println(l)
+	}
+	if len(drifted) > 0 {
+		return ExitDrift
+	}
+	return 0
+}
+
+// handleSinceLast handles keys on the Since Last Run result screen.
+func (m model) handleSinceLast(key string) (tea.Model, tea.Cmd) {
+	switch key {
+	case "o":
+		// The full dry run output, as a plain Dry Run would show it
+		m.screen = screenDone
+		m.message = "Setup checked (dry run) successfully!"
+		m.syncViewport()
+	case "esc", "enter", "q":
+		m.screen = screenMain
+		m.cursor = 0
+		m.outputLines = nil
+	}
+	return m, nil
+}
+
+// viewSinceLast renders the Since Last Run result.
+func (m model) viewSinceLast() string {
+	var b strings.Builder
+	b.WriteString(dryRunBadge.Render("SINCE LAST RUN"))
+	if len(m.recap.Hosts) > 0 {
+		b.WriteString("  " + renderRecap(m.recap.Total()))
+	}
+	b.WriteString("\n\n")
+
+	switch {
+	case m.lastRun == nil:
+		b.WriteString(subtitleStyle.Render("No earlier run recorded; this dry run is the baseline from now on.") + "\n")
+	case len(m.drifted) == 0 && len(m.stillChanged) == 0:
+		b.WriteString(successStyle.Render("✓ No drift since "+lastRunLabel(m.lastRun)) + "\n")
+	case len(m.drifted) == 0:
+		b.WriteString(successStyle.Render("✓ No new drift since "+lastRunLabel(m.lastRun)) + "\n")
+	default:
+		b.WriteString(normalStyle.Render("Compared with "+lastRunLabel(m.lastRun)) + "\n")
+	}
+
+	if len(m.drifted) > 0 {
+		label := "Drifted:"
+		if m.lastRun == nil {
+			label = "Would change:"
+		}
+		b.WriteString("\n  " + warnStyle.Render(label) + "\n")
+		for _, r := range m.drifted {
+			b.WriteString("    " + warnStyle.Render("● "+r) + "\n")
+		}
+	}
+	if len(m.stillChanged) > 0 {
+		b.WriteString("\n  " + configKeyStyle.Render("Still changing, as last time:") + "\n")
+		for _, r := range m.stillChanged {
+			b.WriteString("    " + normalStyle.Render("○ "+r) + "\n")
+		}
+	}
+	b.WriteString(m.renderHelp("o full output • enter/esc menu"))
+	return b.String()
+}
@@ -0,0 +1,110 @@
+package tui
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// helpSection is a group of keybindings shown on screenHelp.
+type helpSection struct {
+	title string
+	keys  [][2]string // key, description
+}
+
+var helpSections = []helpSection{
+	{"Global", [][2]string{
+		{"?", "show this help (except while typing text)"},
+		{"ctrl+c", "cancel a run; press again to quit"},
+	}},
+	{"Menus", [][2]string{
+		{"↑/↓ or k/j", "move"},
+		{"enter", "select"},
+		{"esc", "back"},
+		{"q", "quit (main menu)"},
+	}},
+	{"Role selection", [][2]string{
+		{"space", "toggle the role under the cursor"},
+		{"a", "select all / none"},
+		{"r", "reset to all selected except default_deselected (the last run's choice is remembered)"},
+		{"d", "save the current selection as the default (default_deselected)"},
+		{"/", "filter roles; enter keeps the filter, esc clears it"},
+		{"t", "list the tasks the selected roles would run"},
+		{"v", "cycle ansible verbosity"},
+		{"enter", "run with the selected roles"},
+	}},
+	{"Host selection", [][2]string{
+		{"space", "toggle the host under the cursor"},
+		{"a", "select all / none"},
+		{"enter", "continue with the selected hosts"},
+	}},
+	{"Apply confirmation", [][2]string{
+		{"y/enter", "apply"},
+		{"apply + enter", "apply in safe mode (safe_mode); enter alone does a dry run"},
+		{"n/esc", "back"},
+	}},
+	{"Failed run", [][2]string{
+		{"r", "retry with the same roles, hosts and dry-run setting"},
+		{"f", "retry only the roles that failed or didn't get to run"},
+		{"c", "copy the error and the end of the output to the clipboard"},
+		{"esc", "back to the main menu"},
+	}},
+	{"Since Last Run", [][2]string{
+		{"o", "show the full dry run output"},
+		{"enter/esc", "back to the main menu"},
+	}},
+	{"Config edit", [][2]string{
+		{"↑/↓ or tab", "move between fields"},
+		{"space", "toggle a yes/no field"},
+		{"enter", "confirm the field and move on; save when done (opens the list for extra packages)"},
+		{"esc", "discard changes"},
+	}},
+	{"Extra packages", [][2]string{
+		{"n", "add a package (commas or spaces add several)"},
+		{"e / enter", "edit the package under the cursor"},
+		{"d", "delete the package under the cursor"},
+		{"esc", "back to the config editor"},
+	}},
+	{"Output", [][2]string{
+		{"↑/↓", "scroll"},
+		{"g / G", "jump to top / bottom (follow output)"},
+		{"l", "switch between ansible's output and a list of tasks with their status"},
+	}},
+}
+
+// typingText reports whether keys are currently being typed into a text
+// field, where "?" must be entered literally rather than opening help.
+func (m model) typingText() bool {
+	switch m.screen {
+	case screenPassword:
+		return true
+	case screenRoles:
+		return m.filtering
+	case screenConfigEdit:
+		return !m.editDone && m.editFields[m.editCursor].kind == fieldString
+	case screenPackages:
+		return m.pkgEditing
+	}
+	return false
+}
+
+func (m model) handleHelp(key string) (tea.Model, tea.Cmd) {
+	switch key {
+	case "?", "esc", "q":
+		m.screen = m.helpReturn
+	}
+	return m, nil
+}
+
+func (m model) viewHelp() string {
+	var b strings.Builder
+	b.WriteString(subtitleStyle.Render("Keybindings") + "\n")
+	for _, sec := range helpSections {
+		b.WriteString("\n" + selectedStyle.Render(sec.title) + "\n")
+		for _, k := range sec.keys {
+			b.WriteString("  " + configKeyStyle.Render(k[0]) + " " + normalStyle.Render(k[1]) + "\n")
+		}
+	}
+	b.WriteString(m.renderHelp("? or esc to go back"))
+	return b.String()
+}
@@ -0,0 +1,42 @@
+package tui
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// typedText returns the text a key event inserts into a single-line input.
+// Pasted text arrives as one KeyRunes event holding every rune, so all of
+// them are kept; newlines and other control characters are dropped.
+func typedText(msg tea.KeyMsg) string {
+	switch msg.Type {
+	case tea.KeySpace:
+		return " "
+	case tea.KeyRunes:
+		return strings.Map(func(r rune) rune {
+			if !unicode.IsPrint(r) {
+				return -1
+			}
+			return r
+		}, string(msg.Runes))
+	}
+	return ""
+}
+
+// dropLastRune removes the final character of s, keeping multi-byte
+// characters intact.
+func dropLastRune(s string) string {
+	r := []rune(s)
+	if len(r) == 0 {
+		return s
+	}
+	return string(r[:len(r)-1])
+}
+
+// passwordMask shows one dot per character of pw, followed by the cursor.
+func passwordMask(pw string) string {
+	return strings.Repeat("•", utf8.RuneCountInString(pw)) + "▏"
+}
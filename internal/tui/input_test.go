@@ -0,0 +1,86 @@
+package tui
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func runes(s string) tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+var backspace = tea.KeyMsg{Type: tea.KeyBackspace}
+
+func TestTypedText(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  tea.KeyMsg
+		want string
+	}{
+		{"accented rune", runes("é"), "é"},
+		{"pasted multi-byte text", runes("José Müller"), "José Müller"},
+		{"CJK and emoji", runes("名前🙂"), "名前🙂"},
+		{"control characters dropped", runes("a\nb\tc"), "abc"},
+		{"space", tea.KeyMsg{Type: tea.KeySpace}, " "},
+		{"non-text key", tea.KeyMsg{Type: tea.KeyUp}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := typedText(tt.msg); got != tt.want {
+				t.Errorf("typedText = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDropLastRune(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"José", "Jos"},
+		{"Müller", "Mülle"},
+		{"ü", ""},
+		{"🙂x🙂", "🙂x"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := dropLastRune(tt.in); got != tt.want {
+			t.Errorf("dropLastRune(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestConfigEditMultiByteInput(t *testing.T) {
+	m := model{
+		screen:     screenConfigEdit,
+		editFields: []editField{{key: "git_name", label: "Git name", kind: fieldString}},
+	}
+
+	next, _ := m.handleConfigEdit(runes("José Müller"))
+	m = next.(model)
+	if m.editInput != "José Müller" {
+		t.Fatalf("after paste editInput = %q, want %q", m.editInput, "José Müller")
+	}
+
+	for _, want := range []string{"José Mülle", "José Müll", "José Mül", "José Mü", "José M"} {
+		next, _ = m.handleConfigEdit(backspace)
+		m = next.(model)
+		if m.editInput != want {
+			t.Fatalf("after backspace editInput = %q, want %q", m.editInput, want)
+		}
+	}
+}
+
+func TestPasswordMultiByte(t *testing.T) {
+	m := model{screen: screenPassword}
+	next, _ := m.handlePasswordScreen(runes("pässwörd"))
+	m = next.(model)
+	if got, want := passwordMask(m.password), "••••••••▏"; got != want {
+		t.Errorf("mask = %q, want %q (one dot per character)", got, want)
+	}
+
+	next, _ = m.handlePasswordScreen(backspace)
+	m = next.(model)
+	if m.password != "pässwör" {
+		t.Errorf("after backspace password = %q, want %q", m.password, "pässwör")
+	}
+}
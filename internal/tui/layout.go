@@ -0,0 +1,101 @@
+package tui
+
+import "strings"
+
+// listChrome is the number of rows used around a list screen: header (2),
+// subtitle and blank line (2), status message (2) and help (2).
+const listChrome = 8
+
+// listRows returns how many list items fit on screen, or 0 when the terminal
+// size isn't known yet (render everything).
+func (m model) listRows() int {
+	if m.height == 0 {
+		return 0
+	}
+	rows := m.height - listChrome
+	if rows < 3 {
+		rows = 3
+	}
+	return rows
+}
+
+// scrollWindow returns the offset of a rows-tall window over total items that
+// keeps cursor visible, moving by one as the cursor passes either edge.
+func scrollWindow(cursor, offset, total, rows int) int {
+	if rows <= 0 || total <= rows {
+		return 0
+	}
+	if cursor < offset {
+		offset = cursor
+	}
+	if cursor >= offset+rows {
+		offset = cursor - rows + 1
+	}
+	if offset > total-rows {
+		offset = total - rows
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return offset
+}
+
+// visibleRange returns the [start, end) slice bounds for a window.
+func visibleRange(offset, total, rows int) (int, int) {
+	if rows <= 0 || total <= rows {
+		return 0, total
+	}
+	return offset, offset + rows
+}
+
+// truncate shortens s to at most w cells, ending with an ellipsis.
+func truncate(s string, w int) string {
+	r := []rune(s)
+	if w <= 0 {
+		return ""
+	}
+	if len(r) <= w {
+		return s
+	}
+	if w == 1 {
+		return "…"
+	}
+	return string(r[:w-1]) + "…"
+}
+
+// renderHelp renders the footer help line, wrapped to the terminal width.
+func (m model) renderHelp(text string) string {
+	if m.width > 0 {
+		return helpStyle.Width(m.width).Render(text)
+	}
+	return helpStyle.Render(text)
+}
+
+// renderMenu draws a cursor menu, truncating descriptions to fit the width.
+func (m model) renderMenu(items []menuItem) string {
+	labelWidth := 0
+	for _, item := range items {
+		if n := len([]rune(item.label)); n > labelWidth {
+			labelWidth = n
+		}
+	}
+
+	var b strings.Builder
+	for i, item := range items {
+		cursor := "  "
+		style := normalStyle
+		if i == m.cursor {
+			cursor = "▸ "
+			style = selectedStyle
+		}
+		desc := item.desc
+		if m.width > 0 {
+			// cursor (2) + label + gap (2)
+			desc = truncate(desc, m.width-labelWidth-4)
+		}
+		pad := strings.Repeat(" ", labelWidth-len([]rune(item.label)))
+		b.WriteString(cursor + style.Render(item.label) + pad)
+		b.WriteString("  " + subtitleStyle.Render(desc) + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,52 @@
+package tui
+
+import tea "github.com/charmbracelet/bubbletea"
+
+// headerRows is the number of lines View draws above a menu or role list:
+// the title, its bottom margin, the subtitle and a blank line.
+const headerRows = 4
+
+// handleMouse maps clicks on menu and role rows to the equivalent keys, and
+// the scroll wheel to up/down. Everything else is ignored, so the keyboard
+// remains the primary way to drive the TUI.
+func (m model) handleMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	switch msg.Button {
+	case tea.MouseButtonWheelUp:
+		return m.handleKey(tea.KeyMsg{Type: tea.KeyUp})
+	case tea.MouseButtonWheelDown:
+		return m.handleKey(tea.KeyMsg{Type: tea.KeyDown})
+	case tea.MouseButtonLeft:
+		if msg.Action != tea.MouseActionPress {
+			return m, nil
+		}
+	default:
+		return m, nil
+	}
+
+	row := msg.Y - headerRows
+	switch m.screen {
+	case screenMain:
+		if row >= 0 && row < len(mainMenu) {
+			m.cursor = row
+			return m.handleMainMenu("enter")
+		}
+	case screenConfigMenu:
+		if row >= 0 && row < len(configMenu) {
+			m.cursor = row
+			return m.handleConfigMenu("enter")
+		}
+	case screenRoles:
+		visible := m.visibleRoles()
+		start, end := visibleRange(m.roleScroll, len(visible), m.listRows())
+		if start > 0 {
+			row-- // "▲ more" line
+		}
+		if pos := start + row; row >= 0 && pos < end {
+			// A click ends filter mode, keeping the filter
+			m.filtering = false
+			m.cursor = pos
+			return m.handleRoleSelect(" ")
+		}
+	}
+	return m, nil
+}
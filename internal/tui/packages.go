@@ -0,0 +1,136 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// The extra_packages field of the config editor opens screenPackages, where
+// each package is its own line instead of part of a comma-joined string.
+// The list lives in m.editPackages while editing and replaces ExtraPackages
+// when the config is saved.
+
+// openPackages switches to the package list for the field under the cursor.
+func (m model) openPackages() (tea.Model, tea.Cmd) {
+	m.pkgCursor = 0
+	m.pkgEditing = false
+	m.pkgInput = ""
+	m.pkgScroll = 0
+	m.message = ""
+	m.screen = screenPackages
+	return m, nil
+}
+
+func (m model) handlePackages(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	key := msg.String()
+	if m.pkgEditing {
+		switch key {
+		case "enter":
+			m.commitPackageInput()
+		case "esc":
+			// Drop a line that was added but never filled in
+			if m.editPackages[m.pkgCursor] == "" {
+				m.deletePackage()
+			}
+			m.pkgEditing = false
+		case "backspace":
+			m.pkgInput = dropLastRune(m.pkgInput)
+		default:
+			m.pkgInput += typedText(msg)
+		}
+		m.pkgScroll = scrollWindow(m.pkgCursor, m.pkgScroll, len(m.editPackages), m.listRows())
+		return m, nil
+	}
+
+	switch key {
+	case "up", "k":
+		if m.pkgCursor > 0 {
+			m.pkgCursor--
+		}
+	case "down", "j":
+		if m.pkgCursor < len(m.editPackages)-1 {
+			m.pkgCursor++
+		}
+	case "n":
+		m.pkgCursor = len(m.editPackages)
+		m.editPackages = append(m.editPackages, "")
+		m.pkgInput = ""
+		m.pkgEditing = true
+	case "e", "enter":
+		if len(m.editPackages) > 0 {
+			m.pkgInput = m.editPackages[m.pkgCursor]
+			m.pkgEditing = true
+		}
+	case "d":
+		if len(m.editPackages) > 0 {
+			m.deletePackage()
+		}
+	case "esc", "q":
+		m.editFields[m.editCursor].value = strings.Join(m.editPackages, ", ")
+		m.editInput = m.editFields[m.editCursor].value
+		m.screen = screenConfigEdit
+	}
+	m.pkgScroll = scrollWindow(m.pkgCursor, m.pkgScroll, len(m.editPackages), m.listRows())
+	return m, nil
+}
+
+// commitPackageInput stores the typed text in the line being edited. Input
+// with commas or spaces (e.g. a pasted list) becomes one line per package;
+// blank input removes the line.
+func (m *model) commitPackageInput() {
+	m.pkgEditing = false
+	names := strings.FieldsFunc(m.pkgInput, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+	if len(names) == 0 {
+		m.deletePackage()
+		return
+	}
+	rest := append(names[1:], m.editPackages[m.pkgCursor+1:]...)
+	m.editPackages = append(m.editPackages[:m.pkgCursor], names[0])
+	m.editPackages = append(m.editPackages, rest...)
+}
+
+// deletePackage removes the line under the cursor.
+func (m *model) deletePackage() {
+	m.editPackages = append(m.editPackages[:m.pkgCursor], m.editPackages[m.pkgCursor+1:]...)
+	if m.pkgCursor >= len(m.editPackages) && m.pkgCursor > 0 {
+		m.pkgCursor--
+	}
+}
+
+func (m model) viewPackages() string {
+	var b strings.Builder
+	b.WriteString(subtitleStyle.Render("Extra Packages") + "\n\n")
+	if len(m.editPackages) == 0 {
+		b.WriteString(subtitleStyle.Render("  (none — press n to add one)") + "\n")
+	}
+	start, end := visibleRange(m.pkgScroll, len(m.editPackages), m.listRows())
+	if start > 0 {
+		b.WriteString(subtitleStyle.Render("  ▲ more") + "\n")
+	}
+	for i := start; i < end; i++ {
+		cursor := "  "
+		val := normalStyle.Render(m.editPackages[i])
+		if i == m.pkgCursor {
+			cursor = "▸ "
+			if m.pkgEditing {
+				val = selectedStyle.Render(m.pkgInput + "▏")
+			} else {
+				val = selectedStyle.Render(m.editPackages[i])
+			}
+		}
+		b.WriteString(fmt.Sprintf("%s%s\n", cursor, val))
+	}
+	if end < len(m.editPackages) {
+		b.WriteString(subtitleStyle.Render("  ▼ more") + "\n")
+	}
+	if m.pkgEditing {
+		b.WriteString(m.renderHelp("type a package name • enter keep • esc cancel"))
+	} else {
+		b.WriteString(m.renderHelp("↑/↓ navigate • n new • e/enter edit • d delete • esc done"))
+	}
+	return b.String()
+}
@@ -0,0 +1,78 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/jaydubyaeey/flux/internal/ansible"
+	"github.com/jaydubyaeey/flux/internal/config"
+)
+
+// rememberFailedRoles updates the roles `flux run --retry-failed` picks up.
+// A failed apply stores the roles it left undone; one that failed before
+// any task ran leaves the list alone. A successful apply drops the roles
+// it ran. Failing to write the state only costs convenience.
+func rememberFailedRoles(selected []string, events []ansible.TaskEvent, failed bool) {
+	if failed && len(events) == 0 {
+		return
+	}
+	st, err := config.LoadState()
+	if err != nil {
+		st = &config.State{}
+	}
+	if failed {
+		st.FailedRoles = ansible.RetryRoles(selected, events)
+	} else {
+		done := make(map[string]bool, len(selected))
+		for _, r := range selected {
+			done[r] = true
+		}
+		var left []string
+		for _, r := range st.FailedRoles {
+			if !done[r] {
+				left = append(left, r)
+			}
+		}
+		st.FailedRoles = left
+	}
+	_ = config.SaveState(st)
+}
+
+// taskEvents returns the task list as events for ansible.RetryRoles.
+func (m model) taskEvents() []ansible.TaskEvent {
+	events := make([]ansible.TaskEvent, len(m.taskRows))
+	for i, t := range m.taskRows {
+		events[i] = ansible.TaskEvent{Task: t.name, Status: t.status}
+	}
+	return events
+}
+
+// selectOnly checks exactly the given roles on the role screen.
+func (m *model) selectOnly(roles []string) {
+	keep := make(map[string]bool, len(roles))
+	for _, r := range roles {
+		keep[r] = true
+	}
+	for i, r := range m.roles {
+		m.selected[i] = keep[r]
+	}
+}
+
+// rememberCLIFailedRoles is rememberFailedRoles for `flux run`, reading the
+// task results from the captured output.
+func rememberCLIFailedRoles(tags, skipTags, output string, failed bool) {
+	dir, err := ansible.FindAnsibleDir()
+	if err != nil {
+		return
+	}
+	rememberFailedRoles(config.RunRoles(dir, tags, skipTags), outputEvents(output), failed)
+}
+
+// outputEvents reads the task results from captured playbook output.
+func outputEvents(output string) []ansible.TaskEvent {
+	var parser ansible.PlainEventParser
+	var events []ansible.TaskEvent
+	for _, line := range strings.Split(output, "\n") {
+		events = append(events, parser.Line(line)...)
+	}
+	return events
+}
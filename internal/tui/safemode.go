@@ -0,0 +1,113 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// With safe_mode set in the config nothing is applied until "apply" is
+// typed on the confirmation screen. Enter on its own does a dry run of the
+// same roles instead, whichever menu item started the run, and a Safe
+// Run's apply step asks the same way.
+
+// applyWord must be typed to apply in safe mode.
+const applyWord = "apply"
+
+// safeMode reports whether the config asks for typed confirmation.
+func (m model) safeMode() bool {
+	return m.cfg != nil && m.cfg.SafeMode
+}
+
+// typeToApply feeds a key to the "type apply" prompt. It returns the
+// trimmed input when enter is pressed, and done=false otherwise.
+func (m *model) typeToApply(msg tea.KeyMsg) (input string, done bool) {
+	switch msg.String() {
+	case "enter":
+		input = strings.TrimSpace(m.applyInput)
+		m.applyInput = ""
+		return input, true
+	case "backspace":
+		m.applyInput = dropLastRune(m.applyInput)
+	default:
+		m.applyInput += typedText(msg)
+	}
+	return "", false
+}
+
+// handleSafeModeConfirm is handleConfirm in safe mode.
+func (m model) handleSafeModeConfirm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if msg.String() == "esc" {
+		m.applyInput, m.message = "", ""
+		m.screen = screenRoles
+		return m, nil
+	}
+	input, done := m.typeToApply(msg)
+	if !done {
+		return m, nil
+	}
+	switch input {
+	case applyWord:
+		return m.beginRun()
+	case "":
+		m.dryRun = true
+		return m.beginRun()
+	}
+	m.message = fmt.Sprintf("Type %q to apply, or press enter alone for a dry run", applyWord)
+	return m, nil
+}
+
+// handleSafeModeApply is handleSafeApply in safe mode: the check has run,
+// so enter alone goes back instead of checking again.
+func (m model) handleSafeModeApply(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up":
+		m.autoScroll = false
+		m.viewport.LineUp(1)
+		return m, nil
+	case "down":
+		m.autoScroll = false
+		m.viewport.LineDown(1)
+		return m, nil
+	}
+	input, done := m.typeToApply(msg)
+	if msg.String() != "esc" && !done {
+		return m, nil
+	}
+	if input == applyWord {
+		m.safeApplying = true
+		return m.beginRun()
+	}
+	if input != "" {
+		m.message = fmt.Sprintf("Type %q to apply, or press enter alone to go back", applyWord)
+		return m, nil
+	}
+	m.applyInput, m.message = "", ""
+	m.screen = screenMain
+	m.cursor = 0
+	m.outputLines = nil
+	return m, nil
+}
+
+// viewTypeToApply renders the "type apply" prompt and any error about it.
+func (m model) viewTypeToApply(enterAlone string) string {
+	var b strings.Builder
+	b.WriteString(warnStyle.Render("Safe mode is on.") + " " +
+		normalStyle.Render(fmt.Sprintf("Type %q and press enter to apply; enter alone %s.", applyWord, enterAlone)) + "\n")
+	b.WriteString(selectedStyle.Render("> "+m.applyInput+"▏") + "\n")
+	if m.message != "" {
+		b.WriteString(errorStyle.Render(m.message) + "\n")
+	}
+	return b.String()
+}
+
+// mainMenuItems is mainMenu, with Run Setup marked when safe mode is on.
+func (m model) mainMenuItems() []menuItem {
+	if !m.safeMode() {
+		return mainMenu
+	}
+	items := append([]menuItem(nil), mainMenu...)
+	items[0] = menuItem{"Run Setup [safe mode]", "Safe mode: dry run unless you type apply"}
+	return items
+}
@@ -1,6 +1,11 @@
 package tui
 
-import "github.com/charmbracelet/lipgloss"
+import (
+	"os"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
 
 var (
 	// Colours
@@ -47,14 +52,31 @@ var (
 			Foreground(errorColor).
 			Bold(true)
 
+	warnStyle = lipgloss.NewStyle().
+			Foreground(warnColor).
+			Bold(true)
+
 	helpStyle = lipgloss.NewStyle().
 			Foreground(mutedColor).
 			MarginTop(1)
 
+	// Wide enough for the longest edit label so each field stays on one row
 	configKeyStyle = lipgloss.NewStyle().
 			Foreground(accentColor).
-			Width(18)
+			Width(28)
 
 	configValStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("#E5E7EB"))
 )
+
+// ColorDisabled reports whether NO_COLOR is set (to any non-empty value, per
+// no-color.org).
+func ColorDisabled() bool {
+	return os.Getenv("NO_COLOR") != ""
+}
+
+// DisableColor makes every lipgloss style, including those outside this
+// package, render as plain text with no escape codes.
+func DisableColor() {
+	lipgloss.SetColorProfile(termenv.Ascii)
+}
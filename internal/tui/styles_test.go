@@ -0,0 +1,47 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+func TestStylesPlainWhenColorDisabled(t *testing.T) {
+	prev := lipgloss.ColorProfile()
+	t.Cleanup(func() { lipgloss.SetColorProfile(prev) })
+
+	styles := map[string]lipgloss.Style{
+		"title":    titleStyle,
+		"selected": selectedStyle,
+		"success":  successStyle,
+		"error":    errorStyle,
+		"warn":     warnStyle,
+		"dry-run":  dryRunBadge,
+	}
+
+	// Sanity check: with a colour terminal the styles do emit escapes.
+	lipgloss.SetColorProfile(termenv.TrueColor)
+	if !strings.Contains(errorStyle.Render("x"), "\x1b[") {
+		t.Fatal("errorStyle has no escape codes even with colour enabled")
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	if !ColorDisabled() {
+		t.Fatal("ColorDisabled() = false with NO_COLOR=1")
+	}
+	DisableColor()
+	for name, style := range styles {
+		if out := style.Render("text"); strings.Contains(out, "\x1b") {
+			t.Errorf("%s style rendered %q, want no ANSI codes", name, out)
+		}
+	}
+}
+
+func TestColorDisabledIgnoresEmptyNoColor(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	if ColorDisabled() {
+		t.Error("ColorDisabled() = true with NO_COLOR empty")
+	}
+}
@@ -0,0 +1,77 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/jaydubyaeey/flux/internal/ansible"
+)
+
+// taskRow is one task in the running screen's task list, with the worst
+// status any host has reported for it so far.
+type taskRow struct {
+	name   string
+	status string
+}
+
+// statusRank orders task statuses so a row shows the most notable one.
+var statusRank = map[string]int{
+	ansible.StatusStarted:     0,
+	ansible.StatusSkipped:     1,
+	ansible.StatusOk:          2,
+	ansible.StatusChanged:     3,
+	ansible.StatusFailed:      4,
+	ansible.StatusUnreachable: 5,
+}
+
+// recordTaskEvents updates the task list from one line of playbook output.
+func (m *model) recordTaskEvents(line string) {
+	for _, ev := range m.events.Line(line) {
+		if ev.Status == ansible.StatusStarted {
+			m.taskRows = append(m.taskRows, taskRow{name: ev.Task, status: ev.Status})
+			continue
+		}
+		i := len(m.taskRows) - 1
+		for i >= 0 && m.taskRows[i].name != ev.Task {
+			i--
+		}
+		if i < 0 {
+			m.taskRows = append(m.taskRows, taskRow{name: ev.Task, status: ev.Status})
+			continue
+		}
+		if statusRank[ev.Status] > statusRank[m.taskRows[i].status] {
+			m.taskRows[i].status = ev.Status
+		}
+	}
+}
+
+// viewTaskList renders the task list in the output pane, following the
+// newest task.
+func (m model) viewTaskList() string {
+	var b strings.Builder
+	if len(m.taskRows) == 0 {
+		b.WriteString(subtitleStyle.Render("waiting for the first task..."))
+	}
+	for i, t := range m.taskRows {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		var mark string
+		switch t.status {
+		case ansible.StatusOk:
+			mark = checkStyle.Render("✓")
+		case ansible.StatusChanged:
+			mark = warnStyle.Render("~")
+		case ansible.StatusSkipped:
+			mark = subtitleStyle.Render("-")
+		case ansible.StatusFailed, ansible.StatusUnreachable:
+			mark = errorStyle.Render("✗")
+		default:
+			mark = subtitleStyle.Render("…")
+		}
+		b.WriteString(mark + " " + normalStyle.Render(t.name))
+	}
+	vp := m.viewport
+	vp.SetContent(b.String())
+	vp.GotoBottom()
+	return vp.View()
+}
@@ -1,16 +1,27 @@
 package tui
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 
 	"github.com/jaydubyaeey/flux/internal/ansible"
 	"github.com/jaydubyaeey/flux/internal/config"
+	"github.com/jaydubyaeey/flux/internal/platform"
 	"github.com/jaydubyaeey/flux/internal/updater"
 )
 
@@ -27,6 +38,18 @@ const (
 	screenPassword
 	screenRunning
 	screenDone
+	screenTasks
+	screenUpdateConfirm
+	screenProfiles
+	screenNotWSL
+	screenConfirm
+	screenHelp
+	screenHosts
+	screenWelcome
+	screenError
+	screenSafeApply
+	screenPackages
+	screenSinceLast
 )
 
 // --- menu items ---
@@ -39,6 +62,9 @@ type menuItem struct {
 var mainMenu = []menuItem{
 	{"Run Setup", "Apply configuration to this machine"},
 	{"Dry Run", "Preview changes without applying (--check)"},
+	{"Safe Run", "Dry run first; apply only if the check passes"},
+	{"Since Last Run", "Dry run and show what drifted since the last run"},
+	{"Syntax Check", "Validate playbook syntax without running it"},
 	{"Configure", "View or edit your settings"},
 	{"Update", "Pull latest changes and rebuild flux"},
 	{"Quit", "Exit flux"},
@@ -48,6 +74,7 @@ var configMenu = []menuItem{
 	{"Show Config", "Display current configuration"},
 	{"Edit Config", "Modify settings interactively"},
 	{"Config Path", "Show config file location"},
+	{"Switch Profile", "Use a different saved configuration"},
 	{"Back", "Return to main menu"},
 }
 
@@ -59,54 +86,170 @@ type model struct {
 	dryRun   bool
 	err      error
 	message  string
+	notice   string // short-lived confirmation, e.g. after copying or saving
 	quitting bool
 
+	// Safe Run: a dry run first, then the apply once the check passes and
+	// the user confirms. safeApplying marks the second phase.
+	safe         bool
+	safeApplying bool
+
+	// retryRoles are the roles a failed run left undone, offered on the
+	// error screen's f key.
+	retryRoles []string
+
+	// applyInput is what has been typed to confirm an apply in safe mode.
+	applyInput string
+
+	// Since Last Run: a dry run compared with lastRun, the run recorded
+	// before it started. drifted and stillChanged are the result.
+	sinceLast    bool
+	lastRun      *config.LastRun
+	drifted      []string
+	stillChanged []string
+
 	// Terminal dimensions
 	width  int
 	height int
 
 	// Role selection
-	roles    []string
-	selected map[int]bool
+	roles      []string
+	selected   map[int]bool
+	roleScroll int // first visible role when the list is taller than the screen
+	roleFilter string
+	filtering  bool // typing into roleFilter
+	verbosity  int  // ansible -v count, cycled 0–3 on the role screen
+
+	// Target hosts from the bundled inventory. The host picker is only
+	// shown when it lists more than one host.
+	hosts        []string
+	hostSelected map[int]bool
 
 	// Config
 	cfg          *config.Config
 	configOutput string
+	profiles     []string // shown on screenProfiles; first entry is the default
 
 	// Config edit state
 	editFields []editField
 	editCursor int
+	editScroll int // first visible field when the list is taller than the screen
 	editInput  string
 	editDone   bool
+	editDiff   []config.FieldDiff // on-disk → edited, computed when editing is done
+	editSaved  bool               // a config existed on disk to diff against
+
+	// extra_packages list, edited one package per line on screenPackages
+	editPackages []string
+	pkgCursor    int
+	pkgScroll    int
+	pkgEditing   bool // typing into pkgInput
+	pkgInput     string
 
 	// First-run: config edit was triggered because no config file existed
 	firstRun bool
 
+	// startScreen is shown once the not-under-WSL warning is accepted
+	startScreen screen
+
+	// helpReturn is the screen screenHelp goes back to
+	helpReturn screen
+
 	// Password prompt
 	password     string
 	passwordMask bool
 	needsPass    bool // true when uid != 0
+	passwordless bool // sudo works without a password (NOPASSWD)
+
+	// becomePass is the sudo password remembered for later runs in this
+	// session. It is only ever held in memory and is cleared on quit, after
+	// a run rejects it, and when disable_password_cache is set.
+	becomePass string
 
 	// Ansible output viewport
 	viewport    viewport.Model
 	outputLines []string
 	autoScroll  bool
+	spinner     spinner.Model
+	recap       ansible.Recap // parsed from outputLines when a run finishes
+
+	// Playbook progress: taskTotal comes from ListTasks (0 = unknown),
+	// tasksSeen counts "TASK [" lines, recapSeen marks the final recap.
+	taskTotal int
+	tasksSeen int
+	recapSeen bool
+
+	// Per-task status parsed from the output, shown instead of the raw
+	// output while showTaskList is set (see tasklist.go)
+	events       ansible.PlainEventParser
+	taskRows     []taskRow
+	showTaskList bool
+
+	// Disk space and connectivity warnings shown on the confirm screen
+	preflight     []config.Warning
+	preflightDone bool
+
+	// Pending update shown on the confirm screen
+	updateBehind  int
+	updateSubject string
+	updateStart   time.Time // when the running update began, for the timer
+
+	// cancel aborts the in-flight install/playbook command, if any
+	cancel     context.CancelFunc
+	cancelling bool
 }
 
+type fieldKind int
+
+const (
+	fieldString fieldKind = iota
+	fieldBool
+	fieldList // edited on its own screen, see packages.go
+)
+
 type editField struct {
 	key   string
 	label string
 	value string
+	kind  fieldKind
+}
+
+// editFieldKind reports how a config key is edited: boolean config fields
+// are toggles, extra_packages is a list, everything else is free text.
+func editFieldKind(key string) fieldKind {
+	if config.IsBoolField(key) {
+		return fieldBool
+	}
+	if key == "extra_packages" {
+		return fieldList
+	}
+	return fieldString
 }
 
 func initialModel() model {
+	// Prefer the roles actually on disk; fall back to the built-in list
+	// when the ansible directory can't be located yet.
 	roles := config.AvailableRoles()
-	sel := make(map[int]bool)
-	for i := range roles {
-		sel[i] = true // all selected by default
+	var hosts []string
+	if dir, err := ansible.FindAnsibleDir(); err == nil {
+		roles = config.DiscoverRoles(dir)
+		hosts, _ = ansible.ParseInventoryHosts(filepath.Join(dir, "inventory.ini"))
+	}
+	// Target this machine unless the user picks other hosts
+	hostSel := make(map[int]bool, len(hosts))
+	for i, h := range hosts {
+		hostSel[i] = h == "localhost"
 	}
-
 	cfg, err := config.Load()
+	var deselected []string
+	if cfg != nil {
+		deselected = cfg.DefaultDeselected
+	}
+	var saved map[string]bool
+	if st, err := config.LoadState(); err == nil {
+		saved = st.Roles
+	}
+	sel := initialSelection(roles, deselected, saved)
 
 	vp := viewport.New(80, 20)
 	vp.Style = lipgloss.NewStyle().
@@ -114,33 +257,67 @@ func initialModel() model {
 		BorderForeground(accentColor).
 		Padding(0, 1)
 
+	sp := spinner.New(
+		spinner.WithSpinner(spinner.Dot),
+		spinner.WithStyle(lipgloss.NewStyle().Foreground(accentColor)),
+	)
+
 	m := model{
-		screen:     screenMain,
-		roles:      roles,
-		selected:   sel,
-		cfg:        cfg,
-		viewport:   vp,
-		autoScroll: true,
-		needsPass:  os.Getuid() != 0,
-	}
-
-	// No config file on disk → start on the TUI config-edit screen
-	// so the user can fill in their preferences without blocking stdin.
-	if err != nil || cfg == nil {
+		screen:       screenMain,
+		roles:        roles,
+		selected:     sel,
+		hosts:        hosts,
+		hostSelected: hostSel,
+		cfg:          cfg,
+		viewport:     vp,
+		autoScroll:   true,
+		spinner:      sp,
+		needsPass:    os.Getuid() != 0,
+		passwordless: os.Getuid() != 0 && platform.PasswordlessSudo(),
+	}
+
+	// No config file on disk → welcome the user and set up the essentials
+	// in the TUI, without blocking on stdin. A config that exists but
+	// doesn't load goes straight to the full editor instead.
+	if !config.Exists() {
+		m.cfg = nil
+		m.screen = screenWelcome
+	} else if err != nil || cfg == nil {
 		m.firstRun = true
 		m.cfg = config.DefaultConfig()
 		m.screen = screenConfigEdit
 		m.initEditFields()
 	}
 
+	// The playbooks assume WSL; make the user acknowledge running elsewhere
+	if !platform.IsWSL() {
+		m.startScreen = m.screen
+		m.screen = screenNotWSL
+	}
+
 	return m
 }
 
 // --- messages ---
 
-type playbookDoneMsg struct{ err error }
+type playbookDoneMsg struct {
+	err     error
+	hookErr error
+}
 type updateDoneMsg struct{ err error }
 type playbookOutputMsg struct{ line string }
+type taskTotalMsg struct{ total int }
+type preflightMsg struct{ warnings []config.Warning }
+type syntaxCheckDoneMsg struct{ err error }
+type updateCheckMsg struct {
+	behind  int
+	subject string
+	err     error
+}
+type tasksListedMsg struct {
+	tasks []string
+	err   error
+}
 
 // --- bubbletea interface ---
 
@@ -164,22 +341,93 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		m.viewport.Width = vpWidth
 		m.viewport.Height = vpHeight
-		if m.screen == screenRunning || m.screen == screenDone {
+		if m.screen == screenRunning || m.screen == screenDone || m.screen == screenSafeApply || m.screen == screenTasks {
 			m.syncViewport()
 		}
+		if m.screen == screenRoles {
+			m.roleScroll = scrollWindow(m.cursor, m.roleScroll, len(m.visibleRoles()), m.listRows())
+		}
+		if m.screen == screenConfigEdit {
+			m.editScroll = scrollWindow(m.editCursor, m.editScroll, len(m.editFields), m.listRows())
+		}
+		if m.screen == screenPackages {
+			m.pkgScroll = scrollWindow(m.pkgCursor, m.pkgScroll, len(m.editPackages), m.listRows())
+		}
 		return m, nil
 	case tea.KeyMsg:
-		return m.handleKey(msg)
+		next, cmd := m.handleKey(msg)
+		if nm, ok := next.(model); ok && nm.quitting {
+			nm.becomePass = ""
+			return nm, cmd
+		}
+		return next, cmd
+	case tea.MouseMsg:
+		return m.handleMouse(msg)
 	case playbookOutputMsg:
 		m.outputLines = append(m.outputLines, msg.line)
+		m.recordTaskEvents(msg.line)
+		if strings.HasPrefix(msg.line, "TASK [") {
+			m.tasksSeen++
+		} else if strings.HasPrefix(msg.line, "PLAY RECAP") {
+			m.recapSeen = true
+		}
 		m.syncViewport()
 		return m, nil
+	case taskTotalMsg:
+		m.taskTotal = msg.total
+		return m, nil
+	case preflightMsg:
+		m.preflight, m.preflightDone = msg.warnings, true
+		return m, nil
+	case spinner.TickMsg:
+		// Stop ticking once we've left the running screen
+		if m.screen != screenRunning {
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
 	case playbookDoneMsg:
+		if m.cancel != nil {
+			m.cancel()
+			m.cancel = nil
+		}
+		m.password = ""
 		m.screen = screenDone
 		m.err = msg.err
-		if msg.err != nil {
-			m.outputLines = append(m.outputLines, "", fmt.Sprintf("✗ Playbook failed: %v", msg.err))
+		m.recap = ansible.ParseRecap(strings.Join(m.outputLines, "\n"))
+		m.retryRoles = nil
+		if !m.cancelling && !m.checking() {
+			rememberFailedRoles(m.selectedTags(), m.taskEvents(), msg.err != nil)
+		}
+		if !m.cancelling && msg.err == nil {
+			rememberRun(m.recap, m.taskEvents(), m.checking())
+		}
+		if m.cancelling {
+			m.cancelling = false
+			m.err = context.Canceled
+			m.outputLines = append(m.outputLines, "", "✗ Run cancelled")
+			m.message = "Run cancelled"
+		} else if msg.err != nil {
+			if becomePassRejected(m.outputLines) {
+				m.becomePass = ""
+			}
+			m.screen = screenError
+			if len(m.taskRows) > 0 {
+				m.retryRoles = ansible.RetryRoles(m.selectedTags(), m.taskEvents())
+			}
 			m.message = fmt.Sprintf("Playbook failed: %v", msg.err)
+			if m.safe && !m.safeApplying {
+				m.message = fmt.Sprintf("Check failed, nothing was applied: %v", msg.err)
+			}
+			m.notice = ""
+		} else if m.safe && !m.safeApplying {
+			m.screen = screenSafeApply
+			m.message = ""
+		} else if m.sinceLast {
+			m.drifted, m.stillChanged = compareRuns(m.lastRun, ansible.ChangedRoles(m.taskEvents()))
+			m.screen = screenSinceLast
+			m.message = ""
 		} else {
 			mode := "applied"
 			if m.dryRun {
@@ -187,11 +435,61 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			m.outputLines = append(m.outputLines, "", fmt.Sprintf("✓ Setup %s successfully!", mode))
 			m.message = fmt.Sprintf("Setup %s successfully!", mode)
+			if msg.hookErr != nil {
+				m.outputLines = append(m.outputLines, fmt.Sprintf("⚠ %v", msg.hookErr))
+				m.message += fmt.Sprintf(" (%v)", msg.hookErr)
+			}
+		}
+		m.syncViewport()
+		return m, nil
+	case syntaxCheckDoneMsg:
+		m.screen = screenDone
+		m.err = msg.err
+		if msg.err != nil {
+			m.message = "Syntax check failed"
+			m.outputLines = strings.Split(msg.err.Error(), "\n")
+			m.syncViewport()
+		} else {
+			m.message = "Playbook syntax OK"
+		}
+		return m, nil
+	case tasksListedMsg:
+		if m.screen != screenTasks {
+			return m, nil
+		}
+		m.message = ""
+		switch {
+		case msg.err != nil:
+			m.outputLines = strings.Split(msg.err.Error(), "\n")
+		case len(msg.tasks) == 0:
+			m.outputLines = []string{"No tasks match the selected roles."}
+		default:
+			m.outputLines = msg.tasks
 		}
+		m.autoScroll = false
 		m.syncViewport()
+		m.viewport.GotoTop()
+		return m, nil
+	case updateCheckMsg:
+		switch {
+		case msg.err != nil:
+			m.screen = screenDone
+			m.err = msg.err
+			m.message = fmt.Sprintf("Update check failed: %v", msg.err)
+		case msg.behind == 0:
+			m.screen = screenDone
+			m.err = nil
+			m.message = "flux is already up to date"
+		default:
+			m.screen = screenUpdateConfirm
+			m.updateBehind = msg.behind
+			m.updateSubject = msg.subject
+			m.message = ""
+		}
 		return m, nil
 	case updateDoneMsg:
 		m.screen = screenDone
+		m.updateStart = time.Time{}
 		m.err = msg.err
 		if msg.err != nil {
 			m.message = fmt.Sprintf("Update failed: %v", msg.err)
@@ -217,27 +515,77 @@ func (m model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	// Global keys
 	switch key {
 	case "ctrl+c":
+		// First ctrl+c during a run cancels it; a second one quits
+		if m.screen == screenRunning && m.cancel != nil && !m.cancelling {
+			m.cancel()
+			m.cancelling = true
+			m.outputLines = append(m.outputLines, "", "Cancelling... (ctrl+c again to quit)")
+			m.syncViewport()
+			return m, nil
+		}
+		if m.cancel != nil {
+			m.cancel()
+		}
 		m.quitting = true
 		return m, tea.Quit
+	case "?":
+		if m.screen != screenHelp && !m.typingText() {
+			m.helpReturn = m.screen
+			m.screen = screenHelp
+			return m, nil
+		}
 	}
 
 	switch m.screen {
 	case screenMain:
 		return m.handleMainMenu(key)
 	case screenRoles:
+		if m.filtering {
+			return m.handleRoleFilter(msg)
+		}
 		return m.handleRoleSelect(key)
 	case screenConfigMenu:
 		return m.handleConfigMenu(key)
 	case screenConfigShow:
-		return m.handleAnyKeyBack(key)
+		return m.handleConfigShow(key)
 	case screenDone:
 		return m.handleDoneScreen(key)
+	case screenError:
+		return m.handleErrorScreen(key)
+	case screenSafeApply:
+		if m.safeMode() {
+			return m.handleSafeModeApply(msg)
+		}
+		return m.handleSafeApply(key)
+	case screenSinceLast:
+		return m.handleSinceLast(key)
 	case screenConfigEdit:
-		return m.handleConfigEdit(key)
+		return m.handleConfigEdit(msg)
+	case screenPackages:
+		return m.handlePackages(msg)
 	case screenPassword:
-		return m.handlePasswordScreen(key)
+		return m.handlePasswordScreen(msg)
 	case screenRunning:
 		return m.handleRunningScreen(key)
+	case screenTasks:
+		return m.handleTasksScreen(key)
+	case screenUpdateConfirm:
+		return m.handleUpdateConfirm(key)
+	case screenProfiles:
+		return m.handleProfiles(key)
+	case screenNotWSL:
+		return m.handleNotWSL(key)
+	case screenConfirm:
+		if m.safeMode() {
+			return m.handleSafeModeConfirm(msg)
+		}
+		return m.handleConfirm(key)
+	case screenHelp:
+		return m.handleHelp(key)
+	case screenHosts:
+		return m.handleHosts(key)
+	case screenWelcome:
+		return m.handleWelcome(key)
 	}
 
 	return m, nil
@@ -255,25 +603,40 @@ func (m model) handleMainMenu(key string) (tea.Model, tea.Cmd) {
 		}
 	case "enter":
 		switch m.cursor {
-		case 0: // Run
-			m.dryRun = false
-			m.screen = screenRoles
-			m.cursor = 0
-		case 1: // Dry Run
-			m.dryRun = true
+		case 0, 1, 2, 3: // Run, Dry Run, Safe Run, Since Last Run
+			m.dryRun = m.cursor == 1 || m.cursor == 3
+			m.safe, m.safeApplying = m.cursor == 2, false
+			m.sinceLast = m.cursor == 3
+			if m.sinceLast {
+				m.lastRun = loadLastRun()
+			}
 			m.screen = screenRoles
 			m.cursor = 0
-		case 2: // Configure
+			m.roleScroll = 0
+			m.roleFilter = ""
+		case 4: // Syntax Check
+			m.screen = screenRunning
+			m.outputLines = nil
+			m.message = "Checking playbook syntax..."
+			return m, tea.Batch(m.spinner.Tick, func() tea.Msg {
+				dir, err := ansible.FindAnsibleDir()
+				if err == nil {
+					err = ansible.SyntaxCheck(ansible.RunOptions{AnsibleDir: dir})
+				}
+				return syntaxCheckDoneMsg{err: err}
+			})
+		case 5: // Configure
 			m.screen = screenConfigMenu
 			m.cursor = 0
-		case 3: // Update
+		case 6: // Update
 			m.screen = screenRunning
-			m.message = "Updating flux..."
-			return m, func() tea.Msg {
-				err := updater.Update()
-				return updateDoneMsg{err: err}
-			}
-		case 4: // Quit
+			m.outputLines = nil
+			m.message = "Checking for updates..."
+			return m, tea.Batch(m.spinner.Tick, func() tea.Msg {
+				behind, subject, err := updater.CheckForUpdate()
+				return updateCheckMsg{behind: behind, subject: subject, err: err}
+			})
+		case 7: // Quit
 			m.quitting = true
 			return m, tea.Quit
 		}
@@ -285,38 +648,144 @@ func (m model) handleMainMenu(key string) (tea.Model, tea.Cmd) {
 }
 
 func (m model) handleRoleSelect(key string) (tea.Model, tea.Cmd) {
+	// cursor indexes the filtered view; selected is keyed by real role index
+	visible := m.visibleRoles()
+	m.notice = ""
 	switch key {
 	case "up", "k":
 		if m.cursor > 0 {
 			m.cursor--
 		}
+		m.roleScroll = scrollWindow(m.cursor, m.roleScroll, len(visible), m.listRows())
 	case "down", "j":
-		if m.cursor < len(m.roles)-1 {
+		if m.cursor < len(visible)-1 {
 			m.cursor++
 		}
+		m.roleScroll = scrollWindow(m.cursor, m.roleScroll, len(visible), m.listRows())
 	case " ":
-		m.selected[m.cursor] = !m.selected[m.cursor]
+		if m.cursor < len(visible) {
+			i := visible[m.cursor]
+			m.selected[i] = !m.selected[i]
+		}
 	case "a":
 		allSelected := true
-		for i := range m.roles {
+		for _, i := range visible {
 			if !m.selected[i] {
 				allSelected = false
 				break
 			}
 		}
-		for i := range m.roles {
+		for _, i := range visible {
 			m.selected[i] = !allSelected
 		}
+	case "/":
+		m.filtering = true
+	case "r":
+		// Reset to every role selected bar default_deselected
+		for i := range m.roles {
+			m.selected[i] = true
+		}
+		if m.cfg != nil {
+			applyDefaultDeselected(m.selected, m.roles, m.cfg.DefaultDeselected)
+		}
+	case "d":
+		if m.cfg == nil {
+			m.message = "Set up a config first"
+			return m, nil
+		}
+		// Save the current selection as the default for future launches
+		var deselected []string
+		for i, r := range m.roles {
+			if !m.selected[i] {
+				deselected = append(deselected, r)
+			}
+		}
+		m.cfg.DefaultDeselected = deselected
+		if err := config.Save(m.cfg); err != nil {
+			m.message = fmt.Sprintf("Error saving config: %v", err)
+			return m, nil
+		}
+		m.message = ""
+		m.notice = "Saved as the default selection"
+		return m, nil
+	case "v":
+		m.verbosity = (m.verbosity + 1) % 4
+	case "t":
+		tags := m.selectedTags()
+		if len(tags) == 0 {
+			m.message = "No roles selected"
+			return m, nil
+		}
+		m.screen = screenTasks
+		m.message = "Listing tasks..."
+		m.outputLines = nil
+		m.syncViewport()
+		tagStr := strings.Join(tags, ",")
+		var skipTags string
+		if m.cfg != nil {
+			skipTags = m.cfg.DryRunSkipTags(m.checking())
+		}
+		var hosts []string
+		if len(m.hosts) > 1 {
+			hosts = m.selectedHosts()
+		}
+		return m, func() tea.Msg {
+			dir, err := ansible.FindAnsibleDir()
+			if err != nil {
+				return tasksListedMsg{err: err}
+			}
+			inventory, connection, limit := hostTarget(dir, hosts)
+			tasks, err := ansible.ListTasks(ansible.RunOptions{
+				AnsibleDir: dir,
+				Inventory:  inventory,
+				Connection: connection,
+				Limit:      limit,
+				Tags:       tagStr,
+				SkipTags:   skipTags,
+			})
+			return tasksListedMsg{tasks: tasks, err: err}
+		}
 	case "enter":
 		m.message = "" // clear any stale message before running
 		return m.executePlaybook()
 	case "esc":
+		m.roleFilter = ""
 		m.screen = screenMain
 		m.cursor = 0
 	}
 	return m, nil
 }
 
+// handleRoleFilter edits the role filter string while in filter mode.
+func (m model) handleRoleFilter(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter":
+		m.filtering = false
+	case "esc":
+		m.filtering = false
+		m.roleFilter = ""
+	case "backspace":
+		m.roleFilter = dropLastRune(m.roleFilter)
+	default:
+		m.roleFilter += typedText(msg)
+	}
+	m.cursor = 0
+	m.roleScroll = 0
+	return m, nil
+}
+
+// visibleRoles returns the indices of roles matching the current filter.
+func (m model) visibleRoles() []int {
+	filter := strings.ToLower(m.roleFilter)
+	var idx []int
+	for i, r := range m.roles {
+		if filter == "" || strings.Contains(strings.ToLower(r), filter) {
+			idx = append(idx, i)
+		}
+	}
+	return idx
+}
+
 func (m model) handleConfigMenu(key string) (tea.Model, tea.Cmd) {
 	switch key {
 	case "up", "k":
@@ -346,7 +815,22 @@ func (m model) handleConfigMenu(key string) (tea.Model, tea.Cmd) {
 		case 2: // Path
 			m.screen = screenConfigShow
 			m.configOutput = config.FilePath()
-		case 3: // Back
+		case 3: // Switch Profile
+			names, err := config.ListProfiles()
+			if err != nil {
+				m.screen = screenConfigShow
+				m.configOutput = fmt.Sprintf("Cannot list profiles: %v", err)
+				break
+			}
+			m.profiles = append([]string{config.DefaultProfile}, names...)
+			m.screen = screenProfiles
+			m.cursor = 0
+			for i, name := range m.profiles {
+				if name == config.ActiveProfile() {
+					m.cursor = i
+				}
+			}
+		case 4: // Back
 			m.screen = screenMain
 			m.cursor = 0
 		}
@@ -357,6 +841,49 @@ func (m model) handleConfigMenu(key string) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+func (m model) handleProfiles(key string) (tea.Model, tea.Cmd) {
+	switch key {
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case "down", "j":
+		if m.cursor < len(m.profiles)-1 {
+			m.cursor++
+		}
+	case "enter":
+		name := m.profiles[m.cursor]
+		m.screen = screenConfigShow
+		if err := config.UseProfile(name); err != nil {
+			m.configOutput = fmt.Sprintf("Cannot switch profile: %v", err)
+			break
+		}
+		if cfg, err := config.Load(); err == nil {
+			m.cfg = cfg
+		}
+		m.configOutput = fmt.Sprintf("Active profile: %s\n%s", name, config.FilePath())
+	case "esc":
+		m.screen = screenConfigMenu
+		m.cursor = 3
+	}
+	return m, nil
+}
+
+// handleConfigShow copies the shown config or path with y; other keys go
+// back as usual.
+func (m model) handleConfigShow(key string) (tea.Model, tea.Cmd) {
+	if key == "y" {
+		if err := copyToClipboard(m.configOutput); err != nil {
+			m.notice = fmt.Sprintf("Copy failed: %v", err)
+		} else {
+			m.notice = "Copied!"
+		}
+		return m, nil
+	}
+	m.notice = ""
+	return m.handleAnyKeyBack(key)
+}
+
 func (m model) handleAnyKeyBack(key string) (tea.Model, tea.Cmd) {
 	switch key {
 	case "esc", "enter", "q":
@@ -386,27 +913,23 @@ func (m model) handleDoneScreen(key string) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
-func (m model) handlePasswordScreen(key string) (tea.Model, tea.Cmd) {
-	switch key {
+func (m model) handlePasswordScreen(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
 	case "enter":
-		if m.password == "" {
-			m.message = "Password cannot be empty"
-			return m, nil
-		}
+		// An empty password falls back to ansible's --ask-become-pass
 		m.message = ""
+		if m.password != "" && !m.cfg.DisablePasswordCache {
+			m.becomePass = m.password
+		}
 		return m.startPlaybook()
 	case "backspace":
-		if len(m.password) > 0 {
-			m.password = m.password[:len(m.password)-1]
-		}
+		m.password = dropLastRune(m.password)
 	case "esc":
 		m.password = ""
 		m.screen = screenRoles
 		m.cursor = 0
 	default:
-		if len(key) == 1 {
-			m.password += key
-		}
+		m.password += typedText(msg)
 	}
 	return m, nil
 }
@@ -425,18 +948,151 @@ func (m model) handleRunningScreen(key string) (tea.Model, tea.Cmd) {
 	case "g":
 		m.autoScroll = false
 		m.viewport.GotoTop()
+	case "l":
+		m.showTaskList = !m.showTaskList
+	}
+	return m, nil
+}
+
+func (m model) handleUpdateConfirm(key string) (tea.Model, tea.Cmd) {
+	switch key {
+	case "y", "enter":
+		m.screen = screenRunning
+		m.message = "Updating flux..."
+		m.outputLines = nil
+		m.autoScroll = true
+		m.syncViewport()
+		m.updateStart = time.Now()
+		return m, tea.Batch(m.spinner.Tick, func() tea.Msg {
+			unlock, err := config.Lock()
+			if err != nil {
+				return updateDoneMsg{err: err}
+			}
+			defer unlock()
+			err = updater.UpdateStreaming(func(line string) {
+				programRef.Send(playbookOutputMsg{line: line})
+			})
+			return updateDoneMsg{err: err}
+		})
+	case "n", "esc", "q":
+		m.screen = screenMain
+		m.cursor = 0
+	}
+	return m, nil
+}
+
+// welcomeFields are the settings asked for on first run; everything else
+// keeps its default until edited from the config menu.
+var welcomeFields = map[string]bool{
+	"username": true, "email": true, "git_name": true, "git_email": true,
+	"git_https": true, "default_shell": true,
+}
+
+func (m model) handleWelcome(key string) (tea.Model, tea.Cmd) {
+	switch key {
+	case "enter":
+		m.firstRun = true
+		m.cfg = config.DefaultConfig()
+		m.initEditFields()
+		fields := m.editFields[:0]
+		for _, f := range m.editFields {
+			if welcomeFields[f.key] {
+				fields = append(fields, f)
+			}
+		}
+		m.editFields = fields
+		m.editInput = m.editFields[0].value
+		m.editCursor = 0
+		m.editDone = false
+		m.message = ""
+		m.screen = screenConfigEdit
+	case "q", "esc":
+		m.quitting = true
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m model) handleHosts(key string) (tea.Model, tea.Cmd) {
+	switch key {
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case "down", "j":
+		if m.cursor < len(m.hosts)-1 {
+			m.cursor++
+		}
+	case " ":
+		m.hostSelected[m.cursor] = !m.hostSelected[m.cursor]
+	case "a":
+		all := len(m.selectedHosts()) == len(m.hosts)
+		for i := range m.hosts {
+			m.hostSelected[i] = !all
+		}
+	case "enter":
+		if len(m.selectedHosts()) == 0 {
+			m.message = "No hosts selected"
+			return m, nil
+		}
+		m.message = ""
+		return m.executePlaybook()
+	case "esc":
+		m.message = ""
+		m.screen = screenRoles
+		m.cursor = 0
+	}
+	return m, nil
+}
+
+func (m model) handleConfirm(key string) (tea.Model, tea.Cmd) {
+	switch key {
+	case "y", "enter":
+		return m.beginRun()
+	case "n", "esc", "q":
+		m.screen = screenRoles
+	}
+	return m, nil
+}
+
+func (m model) handleNotWSL(key string) (tea.Model, tea.Cmd) {
+	switch key {
+	case "y":
+		m.screen = m.startScreen
+	case "n", "esc", "q":
+		m.quitting = true
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m model) handleTasksScreen(key string) (tea.Model, tea.Cmd) {
+	switch key {
+	case "up", "k":
+		m.viewport.LineUp(1)
+	case "down", "j":
+		m.viewport.LineDown(1)
+	case "esc", "enter", "q":
+		m.screen = screenRoles
+		m.message = ""
+		m.outputLines = nil
 	}
 	return m, nil
 }
 
-func (m model) handleConfigEdit(key string) (tea.Model, tea.Cmd) {
+func (m model) handleConfigEdit(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	key := msg.String()
 	if m.editDone {
 		switch key {
 		case "enter":
-			// Save on enter
+			// Save on enter, unless nothing changed
 			m.applyEditFields()
-			if err := config.Save(m.cfg); err != nil {
+			if m.editSaved && len(m.editDiff) == 0 {
+				m.message = "No changes to save"
+			} else if err := config.Save(m.cfg); err != nil {
+				// Stay here so the edits aren't lost and enter can retry
 				m.message = fmt.Sprintf("Error saving: %v", err)
+				return m, nil
 			}
 			if m.firstRun {
 				// First-run save complete — go to main menu
@@ -466,24 +1122,41 @@ func (m model) handleConfigEdit(key string) (tea.Model, tea.Cmd) {
 			m.editCursor--
 			m.editInput = m.editFields[m.editCursor].value
 		}
+		m.editScroll = scrollWindow(m.editCursor, m.editScroll, len(m.editFields), m.listRows())
 	case "down", "tab":
+		// Past the last field (which enter can't leave if it's a toggle)
+		// goes on to the review
 		if m.editCursor < len(m.editFields)-1 {
 			m.editCursor++
 			m.editInput = m.editFields[m.editCursor].value
+		} else {
+			m.editFields[m.editCursor].value = m.editInput
+			m.reviewEdits()
 		}
+		m.editScroll = scrollWindow(m.editCursor, m.editScroll, len(m.editFields), m.listRows())
 	case "enter":
+		switch m.editFields[m.editCursor].kind {
+		case fieldList:
+			return m.openPackages()
+		case fieldBool:
+			m.editInput = config.BoolStr(!parseBool(m.editInput))
+			m.editFields[m.editCursor].value = m.editInput
+			return m, nil
+		}
 		// Save current field value
 		m.editFields[m.editCursor].value = m.editInput
 		if m.editCursor < len(m.editFields)-1 {
 			m.editCursor++
 			m.editInput = m.editFields[m.editCursor].value
+			m.editScroll = scrollWindow(m.editCursor, m.editScroll, len(m.editFields), m.listRows())
 		} else {
-			m.editDone = true
+			m.reviewEdits()
 		}
 	case "backspace":
-		if len(m.editInput) > 0 {
-			m.editInput = m.editInput[:len(m.editInput)-1]
+		if m.editFields[m.editCursor].kind != fieldString {
+			break
 		}
+		m.editInput = dropLastRune(m.editInput)
 	case "esc":
 		if m.firstRun {
 			// Can't skip config on first run
@@ -491,14 +1164,35 @@ func (m model) handleConfigEdit(key string) (tea.Model, tea.Cmd) {
 		}
 		m.screen = screenConfigMenu
 		m.cursor = 0
-	default:
-		if len(key) == 1 {
+	case " ":
+		if m.editFields[m.editCursor].kind == fieldBool {
+			m.editInput = config.BoolStr(!parseBool(m.editInput))
+			m.editFields[m.editCursor].value = m.editInput
+			break
+		}
+		if m.editFields[m.editCursor].kind == fieldString {
 			m.editInput += key
 		}
+	default:
+		// Booleans are toggled and lists have their own screen
+		if m.editFields[m.editCursor].kind == fieldString {
+			m.editInput += typedText(msg)
+		}
 	}
 	return m, nil
 }
 
+// reviewEdits ends field editing and shows what saving would change.
+func (m *model) reviewEdits() {
+	m.editDone = true
+	m.message = ""
+	m.editDiff, m.editSaved = nil, false
+	if onDisk, err := config.Load(); err == nil {
+		m.editDiff = config.Diff(onDisk, m.editedConfig())
+		m.editSaved = true
+	}
+}
+
 func (m *model) initEditFields() {
 	cfg := m.cfg
 	if cfg == nil {
@@ -506,136 +1200,416 @@ func (m *model) initEditFields() {
 		m.cfg = cfg
 	}
 	m.editFields = []editField{
-		{"username", "Username", cfg.Username},
-		{"email", "Email", cfg.Email},
-		{"git_name", "Git Name", cfg.GitName},
-		{"git_email", "Git Email", cfg.GitEmail},
-		{"git_https", "GitHub HTTPS (true/false)", config.BoolStr(cfg.GitHTTPS)},
-		{"default_shell", "Shell (bash/zsh)", cfg.DefaultShell},
-		{"install_podman", "Install Podman (true/false)", config.BoolStr(cfg.InstallPodman)},
-		{"install_bun", "Install Bun (true/false)", config.BoolStr(cfg.InstallBun)},
-		{"install_go", "Install Go (true/false)", config.BoolStr(cfg.InstallGo)},
-		{"go_version", "Go Version (latest)", cfg.GoVersion},
-		{"install_dotnet", "Install .NET (true/false)", config.BoolStr(cfg.InstallDotnet)},
-		{"dotnet_version", ".NET Ver (latest)", cfg.DotnetVersion},
-		{"install_python", "Install Python (true/false)", config.BoolStr(cfg.InstallPython)},
-		{"python_version", "Python Ver (latest)", cfg.PythonVersion},
-		{"install_k9s", "Install k9s (true/false)", config.BoolStr(cfg.InstallK9s)},
-		{"extra_packages", "Extra Packages (csv)", strings.Join(cfg.ExtraPackages, ", ")},
+		{key: "username", label: "Username", value: cfg.Username},
+		{key: "email", label: "Email", value: cfg.Email},
+		{key: "git_name", label: "Git Name", value: cfg.GitName},
+		{key: "git_email", label: "Git Email", value: cfg.GitEmail},
+		{key: "git_https", label: "GitHub HTTPS", value: config.BoolStr(cfg.GitHTTPS)},
+		{key: "default_shell", label: "Shell (bash/zsh/fish)", value: cfg.DefaultShell},
+		{key: "install_podman", label: "Install Podman", value: config.BoolStr(cfg.InstallPodman)},
+		{key: "podman_wsl_distro", label: "Podman WSL Distro", value: cfg.PodmanWSLDistro},
+		{key: "podman_wsl_host", label: "Podman WSL Host", value: cfg.PodmanWSLHost},
+		{key: "podman_wsl_port", label: "Podman WSL Port", value: cfg.PodmanWSLPort},
+		{key: "install_bun", label: "Install Bun", value: config.BoolStr(cfg.InstallBun)},
+		{key: "install_go", label: "Install Go", value: config.BoolStr(cfg.InstallGo)},
+		{key: "go_version", label: "Go Version (latest)", value: cfg.GoVersion},
+		{key: "install_dotnet", label: "Install .NET", value: config.BoolStr(cfg.InstallDotnet)},
+		{key: "dotnet_version", label: ".NET Ver (latest)", value: cfg.DotnetVersion},
+		{key: "install_python", label: "Install Python", value: config.BoolStr(cfg.InstallPython)},
+		{key: "python_version", label: "Python Ver (latest)", value: cfg.PythonVersion},
+		{key: "install_k9s", label: "Install k9s", value: config.BoolStr(cfg.InstallK9s)},
+		{key: "extra_packages", label: "Extra Packages", value: strings.Join(cfg.ExtraPackages, ", ")},
+		{key: "check_mode_excluded_roles", label: "Dry-run Skip Roles (csv)", value: strings.Join(cfg.CheckModeExcludedRoles, ", ")},
+		{key: "default_deselected", label: "Unchecked Roles (csv)", value: strings.Join(cfg.DefaultDeselected, ", ")},
+		{key: "log_file", label: "Log File (optional)", value: cfg.LogFile},
+		{key: "disable_password_cache", label: "Don't Cache Sudo Pass", value: config.BoolStr(cfg.DisablePasswordCache)},
+		{key: "no_become_pass", label: "No Sudo Password", value: config.BoolStr(cfg.NoBecomePass)},
+		{key: "safe_mode", label: "Safe Mode", value: config.BoolStr(cfg.SafeMode)},
 	}
+	for i := range m.editFields {
+		m.editFields[i].kind = editFieldKind(m.editFields[i].key)
+	}
+	m.editPackages = append([]string(nil), cfg.ExtraPackages...)
 	m.editInput = m.editFields[0].value
+	m.editScroll = 0
 }
 
 func (m *model) applyEditFields() {
-	if m.cfg == nil {
-		m.cfg = config.DefaultConfig()
+	m.cfg = m.editedConfig()
+}
+
+// editedConfig returns a copy of the config with the edit fields applied,
+// leaving m.cfg untouched so the change can be previewed first.
+func (m model) editedConfig() *config.Config {
+	base := m.cfg
+	if base == nil {
+		base = config.DefaultConfig()
 	}
+	cfg := *base
 	for _, f := range m.editFields {
 		switch f.key {
 		case "username":
-			m.cfg.Username = f.value
+			cfg.Username = f.value
 		case "email":
-			m.cfg.Email = f.value
+			cfg.Email = f.value
 		case "git_name":
-			m.cfg.GitName = f.value
+			cfg.GitName = f.value
 		case "git_email":
-			m.cfg.GitEmail = f.value
+			cfg.GitEmail = f.value
 		case "git_https":
-			m.cfg.GitHTTPS = parseBool(f.value)
+			cfg.GitHTTPS = parseBool(f.value)
 		case "default_shell":
-			m.cfg.DefaultShell = f.value
+			cfg.DefaultShell = f.value
 		case "install_podman":
-			m.cfg.InstallPodman = parseBool(f.value)
+			cfg.InstallPodman = parseBool(f.value)
+		case "podman_wsl_distro":
+			cfg.PodmanWSLDistro = f.value
+		case "podman_wsl_host":
+			cfg.PodmanWSLHost = f.value
+		case "podman_wsl_port":
+			cfg.PodmanWSLPort = f.value
 		case "install_bun":
-			m.cfg.InstallBun = parseBool(f.value)
+			cfg.InstallBun = parseBool(f.value)
 		case "install_go":
-			m.cfg.InstallGo = parseBool(f.value)
+			cfg.InstallGo = parseBool(f.value)
 		case "go_version":
-			m.cfg.GoVersion = f.value
+			cfg.GoVersion = f.value
 		case "install_dotnet":
-			m.cfg.InstallDotnet = parseBool(f.value)
+			cfg.InstallDotnet = parseBool(f.value)
 		case "dotnet_version":
-			m.cfg.DotnetVersion = f.value
+			cfg.DotnetVersion = f.value
 		case "install_python":
-			m.cfg.InstallPython = parseBool(f.value)
+			cfg.InstallPython = parseBool(f.value)
 		case "python_version":
-			m.cfg.PythonVersion = f.value
+			cfg.PythonVersion = f.value
 		case "install_k9s":
-			m.cfg.InstallK9s = parseBool(f.value)
+			cfg.InstallK9s = parseBool(f.value)
 		case "extra_packages":
-			m.cfg.ExtraPackages = nil
+			cfg.ExtraPackages = append([]string(nil), m.editPackages...)
+		case "log_file":
+			cfg.LogFile = f.value
+		case "disable_password_cache":
+			cfg.DisablePasswordCache = parseBool(f.value)
+		case "no_become_pass":
+			cfg.NoBecomePass = parseBool(f.value)
+		case "safe_mode":
+			cfg.SafeMode = parseBool(f.value)
+		case "check_mode_excluded_roles":
+			cfg.CheckModeExcludedRoles = nil
 			for _, p := range strings.Split(f.value, ",") {
 				p = strings.TrimSpace(p)
 				if p != "" {
-					m.cfg.ExtraPackages = append(m.cfg.ExtraPackages, p)
+					cfg.CheckModeExcludedRoles = append(cfg.CheckModeExcludedRoles, p)
 				}
 			}
-		}
-	}
-}
+		case "default_deselected":
+			cfg.DefaultDeselected = nil
+			for _, p := range strings.Split(f.value, ",") {
+				p = strings.TrimSpace(p)
+				if p != "" {
+					cfg.DefaultDeselected = append(cfg.DefaultDeselected, p)
+				}
+			}
+		}
+	}
+	return &cfg
+}
 
 func (m model) executePlaybook() (model, tea.Cmd) {
 	// Ensure config exists — redirect to TUI edit screen instead of
 	// calling the stdin-based LoadOrCreate which conflicts with Bubbletea.
 	if m.cfg == nil {
-		m.firstRun = true
-		m.cfg = config.DefaultConfig()
-		m.screen = screenConfigEdit
-		m.editCursor = 0
-		m.editDone = false
-		m.initEditFields()
+		m.screen = screenWelcome
 		m.message = "Please configure flux before running."
 		return m, nil
 	}
 
-	// Build tags from selection
-	var tags []string
-	for i, r := range m.roles {
-		if m.selected[i] {
-			tags = append(tags, r)
-		}
-	}
-	if len(tags) == 0 {
+	if len(m.selectedTags()) == 0 {
 		m.message = "No roles selected"
 		return m, nil
 	}
 
+	// With several inventory hosts, pick the targets first
+	if len(m.hosts) > 1 && m.screen != screenHosts {
+		m.screen = screenHosts
+		m.cursor = 0
+		m.message = ""
+		return m, nil
+	}
+
+	// Real applies change the system, so summarise and ask first. Safe
+	// runs ask once their check has passed instead.
+	if !m.dryRun && !m.safe {
+		m.screen = screenConfirm
+		m.message = ""
+		m.preflight, m.preflightDone = nil, false
+		cfg, roles := m.cfg, m.selectedTags()
+		local := len(m.hosts) <= 1 || localOnly(m.selectedHosts())
+		return m, func() tea.Msg {
+			warnings := config.PreflightCheck(cfg, ansible.Offline())
+			if w := config.MemoryCheck(cfg, roles); w != nil && local {
+				warnings = append([]config.Warning{*w}, warnings...)
+			}
+			return preflightMsg{warnings: warnings}
+		}
+	}
+	return m.beginRun()
+}
+
+// checking reports whether the next or current run is in check mode: a dry
+// run, or the first phase of a safe run.
+func (m model) checking() bool {
+	return m.dryRun || (m.safe && !m.safeApplying)
+}
+
+// handleSafeApply asks whether to apply once a safe run's check passed.
+func (m model) handleSafeApply(key string) (tea.Model, tea.Cmd) {
+	switch key {
+	case "up", "k":
+		m.autoScroll = false
+		m.viewport.LineUp(1)
+	case "down", "j":
+		m.autoScroll = false
+		m.viewport.LineDown(1)
+	case "y", "enter":
+		m.safeApplying = true
+		return m.beginRun()
+	case "n", "esc", "q":
+		m.screen = screenMain
+		m.cursor = 0
+		m.outputLines = nil
+	}
+	return m, nil
+}
+
+// becomePassRejected reports whether ansible's output says the become
+// password was wrong, so a cached one must not be reused.
+func becomePassRejected(lines []string) bool {
+	for _, l := range lines {
+		l = strings.ToLower(l)
+		if strings.Contains(l, "incorrect sudo password") || strings.Contains(l, "incorrect su password") {
+			return true
+		}
+	}
+	return false
+}
+
+// handleErrorScreen lets a failed run be retried with the same roles, hosts
+// and dry-run flag, or its details copied for a bug report.
+func (m model) handleErrorScreen(key string) (tea.Model, tea.Cmd) {
+	switch key {
+	case "r":
+		m.err = nil
+		return m.beginRun()
+	case "f":
+		// Only offered when it would run less than r does
+		if len(m.retryRoles) > 0 && len(m.retryRoles) < len(m.selectedTags()) {
+			m.err = nil
+			m.selectOnly(m.retryRoles)
+			return m.beginRun()
+		}
+	case "c":
+		if err := copyToClipboard(m.errorReport()); err != nil {
+			m.notice = fmt.Sprintf("Copy failed: %v", err)
+		} else {
+			m.notice = "Copied error details to the clipboard"
+		}
+	case "esc", "enter", "q":
+		m.screen = screenMain
+		m.cursor = 0
+		m.err = nil
+		m.message = ""
+		m.notice = ""
+		m.outputLines = nil
+	}
+	return m, nil
+}
+
+// errorTailLines is how much of the failed run's output screenError shows.
+const errorTailLines = 15
+
+// outputTail returns the last n lines of output, ignoring trailing blanks.
+func (m model) outputTail(n int) []string {
+	lines := m.outputLines
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
+
+// failedTagsLabel describes the roles the failed run was asked to apply.
+func (m model) failedTagsLabel() string {
+	tags := m.selectedTags()
+	if len(tags) == len(m.roles) {
+		return "all roles"
+	}
+	return strings.Join(tags, ", ")
+}
+
+// errorReport is the plain-text summary copied from screenError.
+func (m model) errorReport() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "flux: %s\n", m.message)
+	fmt.Fprintf(&b, "tags: %s\n", m.failedTagsLabel())
+	if m.checking() {
+		b.WriteString("mode: dry run\n")
+	}
+	if errors.Is(m.err, ansible.ErrKilled) {
+		b.WriteString(ansible.MemoryHint + "\n")
+	}
+	b.WriteString("\n")
+	for _, l := range m.outputTail(50) {
+		b.WriteString(l + "\n")
+	}
+	return b.String()
+}
+
+// beginRun clears the previous output and starts the run, asking for the
+// sudo password first when needed.
+func (m model) beginRun() (model, tea.Cmd) {
 	// Clear previous output
 	m.outputLines = nil
 	m.autoScroll = true
 	m.message = ""
 
-	// If not root, prompt for sudo password first
-	if m.needsPass {
+	// If not root, prompt for sudo password first, unless one was
+	// entered earlier in this session
+	if m.needsPass && m.cfg.DisablePasswordCache {
+		m.becomePass = ""
+	}
+	if m.becomeNeeded() && m.becomePass == "" {
 		m.screen = screenPassword
 		m.password = ""
 		return m, nil
 	}
+	m.password = m.becomePass
 
 	// Already root — go straight to running
 	return m.startPlaybook()
 }
 
-// startPlaybook kicks off ansible with streaming output into the viewport.
-func (m model) startPlaybook() (model, tea.Cmd) {
-	m.screen = screenRunning
+// becomeNeeded reports whether the run needs a sudo password: not when
+// running as root, when no_become_pass is set, or when sudo is passwordless
+// and only this machine is targeted.
+func (m model) becomeNeeded() bool {
+	if !m.needsPass || m.cfg.NoBecomePass {
+		return false
+	}
+	local := len(m.hosts) <= 1 || localOnly(m.selectedHosts())
+	return !(m.passwordless && local)
+}
 
-	// Collect parameters for the goroutine closure
+// saveRoleSelection remembers the current selection for the next launch.
+// Failing to write it only costs convenience, so errors are ignored.
+func (m model) saveRoleSelection() {
+	st, err := config.LoadState()
+	if err != nil {
+		st = &config.State{}
+	}
+	st.Roles = make(map[string]bool, len(m.roles))
+	for i, r := range m.roles {
+		st.Roles[r] = m.selected[i]
+	}
+	_ = config.SaveState(st)
+}
+
+// initialSelection is the role selection flux starts with: the last run's
+// (saved), and for roles it doesn't mention, such as on first launch or
+// when a role is new, every role bar those in deselected.
+func initialSelection(roles, deselected []string, saved map[string]bool) map[int]bool {
+	sel := make(map[int]bool, len(roles))
+	for i := range roles {
+		sel[i] = true
+	}
+	applyDefaultDeselected(sel, roles, deselected)
+	for i, r := range roles {
+		if was, ok := saved[r]; ok {
+			sel[i] = was
+		}
+	}
+	return sel
+}
+
+// applyDefaultDeselected unchecks the roles listed in deselected (the
+// config's default_deselected).
+func applyDefaultDeselected(sel map[int]bool, roles, deselected []string) {
+	for i, r := range roles {
+		for _, d := range deselected {
+			if r == d {
+				sel[i] = false
+			}
+		}
+	}
+}
+
+// selectedTags returns the role tags currently checked on the role screen.
+func (m model) selectedTags() []string {
 	var tags []string
 	for i, r := range m.roles {
 		if m.selected[i] {
 			tags = append(tags, r)
 		}
 	}
-	tagStr := strings.Join(tags, ",")
-	dryRun := m.dryRun
+	return tags
+}
+
+// selectedHosts returns the inventory hosts checked on the host screen.
+func (m model) selectedHosts() []string {
+	var hosts []string
+	for i, h := range m.hosts {
+		if m.hostSelected[i] {
+			hosts = append(hosts, h)
+		}
+	}
+	return hosts
+}
+
+// hostTarget returns the inventory, connection and limit to run against
+// hosts. No hosts, or just localhost, is the usual local run; anything else
+// goes through the bundled inventory with --limit, where the localhost entry
+// keeps its ansible_connection=local.
+func hostTarget(ansibleDir string, hosts []string) (inventory, connection, limit string) {
+	if localOnly(hosts) {
+		return "", "", ""
+	}
+	return filepath.Join(ansibleDir, "inventory.ini"), "ssh", strings.Join(hosts, ",")
+}
+
+// localOnly reports whether hosts targets just this machine.
+func localOnly(hosts []string) bool {
+	return len(hosts) == 0 || (len(hosts) == 1 && hosts[0] == "localhost")
+}
+
+// startPlaybook kicks off ansible with streaming output into the viewport.
+func (m model) startPlaybook() (model, tea.Cmd) {
+	m.screen = screenRunning
+
+	// Collect parameters for the goroutine closure
+	tagStr := strings.Join(m.selectedTags(), ",")
+	dryRun := m.checking()
+	verbosity := m.verbosity
 	cfg := m.cfg
 	pass := m.password
+	noPass := !m.becomeNeeded()
+	var hosts []string
+	if len(m.hosts) > 1 {
+		hosts = m.selectedHosts()
+	}
 
 	// Clear password from model immediately
 	m.password = ""
 
-	return m, func() tea.Msg {
+	m.saveRoleSelection()
+	m.taskTotal, m.tasksSeen, m.recapSeen = 0, 0, false
+	m.events, m.taskRows = ansible.PlainEventParser{}, nil
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+
+	return m, tea.Batch(m.spinner.Tick, func() tea.Msg {
 		if programRef == nil {
 			return playbookDoneMsg{err: fmt.Errorf("internal error: program reference not set")}
 		}
@@ -644,17 +1618,66 @@ func (m model) startPlaybook() (model, tea.Cmd) {
 			programRef.Send(playbookOutputMsg{line: line})
 		}
 
-		if err := ansible.EnsureInstalledStreaming(onOutput); err != nil {
+		unlock, err := config.Lock()
+		if err != nil {
+			return playbookDoneMsg{err: err}
+		}
+		defer unlock()
+
+		if err := ansible.EnsureInstalledStreaming(ctx, ansible.InstallMethod(cfg.AnsibleInstallMethod), cfg.InstallTimeoutDuration(), onOutput); err != nil {
 			return playbookDoneMsg{err: err}
 		}
 		ansibleDir, err := ansible.FindAnsibleDir()
 		if err != nil {
 			return playbookDoneMsg{err: err}
 		}
-		extraVars := cfg.ToExtraVars()
-		err = ansible.RunPlaybookStreaming(ansibleDir, extraVars, tagStr, dryRun, pass, onOutput)
-		return playbookDoneMsg{err: err}
-	}
+		// Roles may have changed on disk since the list was loaded
+		if err := config.ValidateTags(tagStr, config.DiscoverRoles(ansibleDir)); err != nil {
+			return playbookDoneMsg{err: err}
+		}
+		fileVars, err := config.LoadExtraVars("")
+		if err != nil {
+			return playbookDoneMsg{err: err}
+		}
+		extraVars := config.MergeVars(cfg.ToExtraVars(), fileVars)
+
+		skipTags := cfg.DryRunSkipTags(dryRun)
+		if skipTags != "" {
+			onOutput(fmt.Sprintf("Note: skipping roles without check-mode support: %s", skipTags))
+		}
+		inventory, connection, limit := hostTarget(ansibleDir, hosts)
+		opts := ansible.RunOptions{
+			AnsibleDir:    ansibleDir,
+			Inventory:     inventory,
+			Connection:    connection,
+			Limit:         limit,
+			ExtraVars:     extraVars,
+			Tags:          tagStr,
+			SkipTags:      skipTags,
+			DryRun:        dryRun,
+			Verbosity:     verbosity,
+			Forks:         cfg.Forks,
+			VaultPassFile: cfg.VaultPasswordFile,
+			BecomePass:    pass,
+			NoBecomePass:  noPass,
+			LogFile:       cfg.LogFile,
+		}
+
+		// Count the tasks alongside the run for the progress bar; if this
+		// fails the running screen just keeps its spinner.
+		go func() {
+			if tasks, err := ansible.ListTasks(opts); err == nil && len(tasks) > 0 {
+				programRef.Send(taskTotalMsg{total: len(tasks)})
+			}
+		}()
+
+		err = ansible.RunPlaybookStreaming(ctx, opts, onOutput)
+		if err != nil || dryRun || cfg.PostRunHook == "" {
+			return playbookDoneMsg{err: err}
+		}
+		onOutput("")
+		return playbookDoneMsg{hookErr: ansible.RunHook(ctx, cfg.PostRunHook, cfg.HookEnv(), onOutput)}
+	})
 }
 
 // programRef holds a reference to the running tea.Program so that background
@@ -676,63 +1699,202 @@ func (m model) View() string {
 	switch m.screen {
 	case screenMain:
 		b.WriteString(subtitleStyle.Render("WSL bootstrap & configuration") + "\n\n")
-		for i, item := range mainMenu {
-			cursor := "  "
-			style := normalStyle
-			if i == m.cursor {
-				cursor = "▸ "
-				style = selectedStyle
-			}
-			b.WriteString(cursor + style.Render(item.label))
-			b.WriteString("  " + subtitleStyle.Render(item.desc) + "\n")
-		}
-		b.WriteString(helpStyle.Render("↑/↓ navigate • enter select • q quit"))
+		b.WriteString(m.renderMenu(m.mainMenuItems()))
+		b.WriteString(m.renderHelp("↑/↓ navigate • enter select • ? help • q quit"))
 
 	case screenRoles:
 		mode := "Run"
 		if m.dryRun {
 			mode = dryRunBadge.Render("DRY RUN")
+		} else if m.safe {
+			mode = dryRunBadge.Render("SAFE RUN")
+		}
+		header := "Select roles to " + mode
+		if m.filtering || m.roleFilter != "" {
+			filter := m.roleFilter
+			if m.filtering {
+				filter += "▏"
+			}
+			header += "  " + selectedStyle.Render("/"+filter)
 		}
-		b.WriteString(subtitleStyle.Render("Select roles to "+mode) + "\n\n")
+		b.WriteString(subtitleStyle.Render(header) + "\n\n")
 
-		for i, role := range m.roles {
+		visible := m.visibleRoles()
+		if len(visible) == 0 {
+			b.WriteString(subtitleStyle.Render("  no roles match") + "\n")
+		}
+		descriptions := config.RoleDescriptions()
+		start, end := visibleRange(m.roleScroll, len(visible), m.listRows())
+		if start > 0 {
+			b.WriteString(subtitleStyle.Render("  ▲ more") + "\n")
+		}
+		for pos := start; pos < end; pos++ {
+			i := visible[pos]
 			cursor := "  "
-			if i == m.cursor {
+			style := normalStyle
+			if pos == m.cursor {
 				cursor = "▸ "
+				style = selectedStyle
 			}
 			check := uncheckStyle.Render("☐")
 			if m.selected[i] {
 				check = checkStyle.Render("☑")
 			}
+			line := fmt.Sprintf("%s%s %s", cursor, check, style.Render(m.roles[i]))
+			if pos == m.cursor {
+				if desc := descriptions[m.roles[i]]; desc != "" {
+					line += "  " + subtitleStyle.Render(desc)
+				}
+			}
+			b.WriteString(line + "\n")
+		}
+		if end < len(visible) {
+			b.WriteString(subtitleStyle.Render("  ▼ more") + "\n")
+		}
+		if m.message != "" {
+			b.WriteString("\n" + errorStyle.Render(m.message) + "\n")
+		} else if m.notice != "" {
+			b.WriteString("\n" + successStyle.Render("✓ "+m.notice) + "\n")
+		}
+		verbose := "off"
+		if m.verbosity > 0 {
+			verbose = "-" + strings.Repeat("v", m.verbosity)
+		}
+		if m.filtering {
+			b.WriteString(m.renderHelp("type to filter • enter done • esc clear"))
+		} else {
+			b.WriteString(m.renderHelp("↑/↓ navigate • space toggle • a all/none • r reset • d save as default • / filter • t list tasks • v verbosity (" + verbose + ") • enter run • esc back"))
+		}
+
+	case screenHelp:
+		b.WriteString(m.viewHelp())
+
+	case screenPackages:
+		b.WriteString(m.viewPackages())
+
+	case screenWelcome:
+		b.WriteString(subtitleStyle.Render("Welcome to flux!") + "\n\n")
+		b.WriteString(normalStyle.Render("flux sets up this WSL instance with Ansible: packages, git, your") + "\n")
+		b.WriteString(normalStyle.Render("shell and dev tools. First, a few essentials: your username, git") + "\n")
+		b.WriteString(normalStyle.Render("identity and shell. Everything else starts from sensible defaults") + "\n")
+		b.WriteString(normalStyle.Render("and can be changed later under Configure.") + "\n")
+		if m.message != "" {
+			b.WriteString("\n" + warnStyle.Render(m.message) + "\n")
+		}
+		b.WriteString(m.renderHelp("enter get started • q quit"))
+
+	case screenHosts:
+		b.WriteString(subtitleStyle.Render("Select hosts to target") + "\n\n")
+		for i, h := range m.hosts {
+			cursor := "  "
 			style := normalStyle
 			if i == m.cursor {
+				cursor = "▸ "
 				style = selectedStyle
 			}
-			b.WriteString(fmt.Sprintf("%s%s %s\n", cursor, check, style.Render(role)))
+			check := uncheckStyle.Render("☐")
+			if m.hostSelected[i] {
+				check = checkStyle.Render("☑")
+			}
+			b.WriteString(fmt.Sprintf("%s%s %s\n", cursor, check, style.Render(h)))
 		}
 		if m.message != "" {
 			b.WriteString("\n" + errorStyle.Render(m.message) + "\n")
 		}
-		b.WriteString(helpStyle.Render("↑/↓ navigate • space toggle • a all/none • enter run • esc back"))
+		b.WriteString(m.renderHelp("↑/↓ navigate • space toggle • a all/none • enter continue • esc back"))
+
+	case screenConfirm:
+		b.WriteString(subtitleStyle.Render("Apply changes?") + "\n\n")
+		mode := "apply (not a dry run)"
+		if m.safeMode() {
+			mode = "apply once confirmed, otherwise a dry run"
+		}
+		b.WriteString(configKeyStyle.Render("Mode") + " " + warnStyle.Render(mode) + "\n")
+		b.WriteString(configKeyStyle.Render("User") + " " + configValStyle.Render(m.cfg.Username) + "\n")
+		roles := configValStyle
+		if m.width > 40 {
+			// Wrap long role lists beside the label rather than under it
+			roles = roles.Width(m.width - 30)
+		}
+		b.WriteString(lipgloss.JoinHorizontal(lipgloss.Top,
+			configKeyStyle.Render("Roles"), " ", roles.Render(strings.Join(m.selectedTags(), ", "))) + "\n")
+		if len(m.hosts) > 1 {
+			b.WriteString(lipgloss.JoinHorizontal(lipgloss.Top,
+				configKeyStyle.Render("Hosts"), " ", roles.Render(strings.Join(m.selectedHosts(), ", "))) + "\n")
+		}
+		b.WriteString("\n")
+		switch {
+		case !m.preflightDone:
+			b.WriteString(subtitleStyle.Render("Checking disk space, memory and connectivity...") + "\n")
+		case len(m.preflight) == 0:
+			b.WriteString(successStyle.Render("✓ Enough disk space and memory, and all download hosts reachable") + "\n")
+		default:
+			for _, w := range m.preflight {
+				b.WriteString(warnStyle.Render("⚠ "+w.Message) + "\n")
+				if w.Check == "memory" {
+					b.WriteString(subtitleStyle.Render(ansible.MemoryHint) + "\n")
+				}
+			}
+		}
+		if m.safeMode() {
+			b.WriteString("\n" + m.viewTypeToApply("does a dry run instead"))
+			b.WriteString(m.renderHelp("type apply + enter • enter dry run • esc back"))
+		} else {
+			b.WriteString(m.renderHelp("y/enter apply • n/esc back"))
+		}
+
+	case screenNotWSL:
+		b.WriteString(warnStyle.Render("⚠ Not running under WSL") + "\n\n")
+		b.WriteString(normalStyle.Render("flux is built to bootstrap WSL. On other systems the apt and") + "\n")
+		b.WriteString(normalStyle.Render("podman-WSL steps may fail or change things you didn't expect.") + "\n")
+		b.WriteString(m.renderHelp("y continue anyway • n/esc quit"))
+
+	case screenUpdateConfirm:
+		b.WriteString(subtitleStyle.Render("Update available") + "\n\n")
+		b.WriteString(fmt.Sprintf("  %s %s\n",
+			selectedStyle.Render(fmt.Sprintf("%d commit(s) behind:", m.updateBehind)),
+			normalStyle.Render(m.updateSubject)))
+		b.WriteString(m.renderHelp("y/enter update • n/esc cancel"))
+
+	case screenTasks:
+		b.WriteString(subtitleStyle.Render("Tasks for: "+strings.Join(m.selectedTags(), ", ")) + "\n")
+		if m.message != "" {
+			b.WriteString("\n" + m.message + "\n")
+		} else {
+			b.WriteString(m.viewport.View() + "\n")
+		}
+		b.WriteString(m.renderHelp("↑/↓ scroll • esc back"))
 
 	case screenConfigMenu:
 		b.WriteString(subtitleStyle.Render("Configuration") + "\n\n")
-		for i, item := range configMenu {
+		b.WriteString(m.renderMenu(configMenu))
+		b.WriteString(m.renderHelp("↑/↓ navigate • enter select • esc back"))
+
+	case screenProfiles:
+		b.WriteString(subtitleStyle.Render("Switch Profile") + "\n\n")
+		active := config.ActiveProfile()
+		for i, name := range m.profiles {
 			cursor := "  "
 			style := normalStyle
 			if i == m.cursor {
 				cursor = "▸ "
 				style = selectedStyle
 			}
-			b.WriteString(cursor + style.Render(item.label))
-			b.WriteString("  " + subtitleStyle.Render(item.desc) + "\n")
+			label := name
+			if name == active {
+				label += " (active)"
+			}
+			b.WriteString(cursor + style.Render(label) + "\n")
 		}
-		b.WriteString(helpStyle.Render("↑/↓ navigate • enter select • esc back"))
+		b.WriteString(m.renderHelp("↑/↓ navigate • enter switch • esc back"))
 
 	case screenConfigShow:
 		b.WriteString(subtitleStyle.Render("Configuration") + "\n\n")
 		b.WriteString(m.configOutput + "\n")
-		b.WriteString(helpStyle.Render("press enter or esc to go back"))
+		if m.notice != "" {
+			b.WriteString("\n" + subtitleStyle.Render(m.notice) + "\n")
+		}
+		b.WriteString(m.renderHelp("y copy • enter/esc back"))
 
 	case screenConfigEdit:
 		if m.firstRun {
@@ -740,65 +1902,165 @@ func (m model) View() string {
 		} else {
 			b.WriteString(subtitleStyle.Render("Edit Configuration") + "\n\n")
 		}
-		for i, f := range m.editFields {
+		start, end := visibleRange(m.editScroll, len(m.editFields), m.listRows())
+		if start > 0 {
+			b.WriteString(subtitleStyle.Render("  ▲ more") + "\n")
+		}
+		for i := start; i < end; i++ {
+			f := m.editFields[i]
 			cursor := "  "
 			if i == m.editCursor && !m.editDone {
 				cursor = "▸ "
 			}
 			label := configKeyStyle.Render(f.label)
+			active := i == m.editCursor && !m.editDone
 			val := f.value
-			if i == m.editCursor && !m.editDone {
-				val = m.editInput + "▏"
+			if active {
+				val = m.editInput
+			}
+			if f.kind == fieldBool {
+				val = "[ ]"
+				if parseBool(f.value) {
+					val = "[x]"
+				}
+			} else if f.kind == fieldList && active {
+				val += "  (enter to edit the list)"
+			} else if active {
+				val += "▏"
+			}
+			if active {
 				val = selectedStyle.Render(val)
 			} else {
 				val = configValStyle.Render(val)
 			}
 			b.WriteString(fmt.Sprintf("%s%s %s\n", cursor, label, val))
 		}
+		if end < len(m.editFields) {
+			b.WriteString(subtitleStyle.Render("  ▼ more") + "\n")
+		}
 		if m.editDone {
-			b.WriteString("\n" + successStyle.Render("✓ Press enter to save"))
+			if m.editSaved {
+				b.WriteString("\n" + m.renderEditDiff())
+			}
+			if m.message != "" {
+				b.WriteString("\n" + errorStyle.Render(m.message) + "\n")
+				b.WriteString("\n" + successStyle.Render("Press enter to try again"))
+			} else {
+				b.WriteString("\n" + successStyle.Render("✓ Press enter to save"))
+			}
 		}
 		if m.firstRun {
-			b.WriteString(helpStyle.Render("↑/↓ navigate • enter confirm field • ctrl+c quit"))
+			b.WriteString(m.renderHelp("↑/↓ navigate • space/enter toggle • enter confirm field • ↓ past the end to finish • ctrl+c quit"))
 		} else {
-			b.WriteString(helpStyle.Render("↑/↓ navigate • enter confirm field • esc cancel"))
+			b.WriteString(m.renderHelp("↑/↓ navigate • space/enter toggle • enter confirm field • ↓ past the end to finish • esc cancel"))
 		}
 
 	case screenPassword:
 		b.WriteString(subtitleStyle.Render("Sudo password required") + "\n\n")
-		mask := strings.Repeat("•", len(m.password)) + "▏"
-		b.WriteString("  Password: " + selectedStyle.Render(mask) + "\n")
+		b.WriteString("  Password: " + selectedStyle.Render(passwordMask(m.password)) + "\n")
 		if m.message != "" {
 			b.WriteString("\n" + errorStyle.Render(m.message) + "\n")
 		}
-		b.WriteString(helpStyle.Render("enter submit • esc back"))
+		b.WriteString(subtitleStyle.Render("  leave empty to fall back to ansible's --ask-become-pass") + "\n")
+		if !m.cfg.DisablePasswordCache {
+			b.WriteString(subtitleStyle.Render("  remembered (in memory only) for further runs until flux exits") + "\n")
+		}
+		b.WriteString(m.renderHelp("enter submit • esc back"))
 
 	case screenRunning:
-		mode := "Applying"
-		if m.dryRun {
-			mode = "Checking (dry run)"
+		mode := "Applying configuration..."
+		if m.message != "" {
+			// Non-playbook tasks (update, syntax check) describe themselves
+			mode = m.message
+		} else if m.safe && !m.safeApplying {
+			mode = dryRunBadge.Render("SAFE RUN 1/2") + " Checking configuration..."
+		} else if m.safe {
+			mode = dryRunBadge.Render("SAFE RUN 2/2") + " Applying configuration..."
+		} else if m.dryRun {
+			mode = dryRunBadge.Render("DRY RUN") + " Checking configuration..."
+		}
+		status := fmt.Sprintf("%s %s", m.spinner.View(), mode)
+		if !m.updateStart.IsZero() {
+			status += "  " + subtitleStyle.Render(time.Since(m.updateStart).Round(time.Second).String())
+		}
+		if m.message == "" && m.taskTotal > 0 {
+			status += "  " + renderProgress(m.progress(), 24)
+		}
+		b.WriteString(status + "\n")
+		if m.showTaskList && m.message == "" {
+			b.WriteString(m.viewTaskList() + "\n")
+		} else {
+			b.WriteString(m.viewport.View() + "\n")
 		}
-		spinner := lipgloss.NewStyle().Foreground(accentColor).Render("⟳")
-		b.WriteString(fmt.Sprintf("%s %s configuration...\n", spinner, mode))
-		b.WriteString(m.viewport.View() + "\n")
 		scrollInfo := subtitleStyle.Render(fmt.Sprintf("lines: %d", len(m.outputLines)))
 		if !m.autoScroll {
 			scrollInfo += subtitleStyle.Render(" (scroll paused)")
 		}
 		b.WriteString(scrollInfo + "\n")
-		b.WriteString(helpStyle.Render("↑/↓ scroll • G bottom • g top • ctrl+c abort"))
+		b.WriteString(m.renderHelp("↑/↓ scroll • G bottom • g top • l task list • ctrl+c cancel"))
 
 	case screenDone:
 		if m.err != nil {
-			b.WriteString("\n" + errorStyle.Render("✗ "+m.message) + "\n")
+			b.WriteString("\n" + errorStyle.Render("✗ "+m.message))
 		} else {
-			b.WriteString("\n" + successStyle.Render("✓ "+m.message) + "\n")
+			b.WriteString("\n" + successStyle.Render("✓ "+m.message))
 		}
+		if len(m.recap.Hosts) > 0 {
+			b.WriteString("  " + renderRecap(m.recap.Total()))
+		}
+		b.WriteString("\n")
 		if len(m.outputLines) > 0 {
 			b.WriteString(m.viewport.View() + "\n")
-			b.WriteString(helpStyle.Render("↑/↓ scroll • enter/esc continue"))
+			b.WriteString(m.renderHelp("↑/↓ scroll • enter/esc continue"))
 		} else {
-			b.WriteString(helpStyle.Render("press enter or esc to continue"))
+			b.WriteString(m.renderHelp("press enter or esc to continue"))
+		}
+
+	case screenSafeApply:
+		b.WriteString(dryRunBadge.Render("SAFE RUN") + " " + successStyle.Render("✓ Check passed"))
+		if len(m.recap.Hosts) > 0 {
+			b.WriteString("  " + renderRecap(m.recap.Total()))
+		}
+		b.WriteString("\n")
+		b.WriteString(m.viewport.View() + "\n")
+		b.WriteString(normalStyle.Render("The dry run found no errors. Apply these changes for real?") + "\n")
+		if m.safeMode() {
+			b.WriteString(m.viewTypeToApply("goes back"))
+			b.WriteString(m.renderHelp("type apply + enter • enter/esc cancel • ↑/↓ scroll"))
+		} else {
+			b.WriteString(m.renderHelp("y/enter apply • n/esc cancel • ↑/↓ scroll"))
+		}
+
+	case screenSinceLast:
+		b.WriteString(m.viewSinceLast())
+
+	case screenError:
+		b.WriteString("\n" + errorStyle.Render("✗ "+m.message))
+		if len(m.recap.Hosts) > 0 {
+			b.WriteString("  " + renderRecap(m.recap.Total()))
+		}
+		b.WriteString("\n\n")
+		b.WriteString(fmt.Sprintf("  %s %s\n", configKeyStyle.Render("Tags:"), configValStyle.Render(m.failedTagsLabel())))
+		if m.checking() {
+			b.WriteString("  " + dryRunBadge.Render("DRY RUN") + "\n")
+		}
+		if errors.Is(m.err, ansible.ErrKilled) {
+			b.WriteString("\n" + warnStyle.Render(ansible.MemoryHint) + "\n")
+		}
+		if tail := m.outputTail(errorTailLines); len(tail) > 0 {
+			b.WriteString("\n" + subtitleStyle.Render("Last output:") + "\n")
+			for _, l := range tail {
+				b.WriteString("  " + l + "\n")
+			}
+		}
+		if m.notice != "" {
+			b.WriteString("\n" + subtitleStyle.Render(m.notice) + "\n")
+		}
+		if len(m.retryRoles) > 0 && len(m.retryRoles) < len(m.selectedTags()) {
+			b.WriteString("\n  " + configKeyStyle.Render("Left undone:") + " " + configValStyle.Render(strings.Join(m.retryRoles, ", ")) + "\n")
+			b.WriteString(m.renderHelp("r retry • f retry undone roles • c copy error • esc menu"))
+		} else {
+			b.WriteString(m.renderHelp("r retry • c copy error • esc menu"))
 		}
 	}
 
@@ -807,16 +2069,93 @@ func (m model) View() string {
 
 // --- helpers ---
 
+// renderEditDiff lists the fields the pending save would change.
+func (m model) renderEditDiff() string {
+	if len(m.editDiff) == 0 {
+		return subtitleStyle.Render("(no changes)") + "\n"
+	}
+	var b strings.Builder
+	b.WriteString(subtitleStyle.Render("Changes:") + "\n")
+	orEmpty := func(v string) string {
+		if v == "" {
+			return "(empty)"
+		}
+		return v
+	}
+	for _, d := range m.editDiff {
+		b.WriteString(fmt.Sprintf("  %s %s %s %s\n",
+			configKeyStyle.Render(d.Key),
+			errorStyle.Render(orEmpty(d.Old)),
+			subtitleStyle.Render("→"),
+			successStyle.Render(orEmpty(d.New))))
+	}
+	return b.String()
+}
+
+// progress estimates how far through the playbook the run is. Loops and
+// includes can run more tasks than ListTasks predicted, so it stays below
+// 100% until the recap is printed.
+func (m model) progress() float64 {
+	if m.recapSeen {
+		return 1
+	}
+	p := float64(m.tasksSeen) / float64(m.taskTotal)
+	if p > 0.99 {
+		p = 0.99
+	}
+	return p
+}
+
+// renderProgress draws a width-cell progress bar followed by the percentage.
+func renderProgress(p float64, width int) string {
+	filled := int(p * float64(width))
+	bar := checkStyle.Render(strings.Repeat("█", filled)) +
+		uncheckStyle.Render(strings.Repeat("░", width-filled))
+	return fmt.Sprintf("%s %3d%%", bar, int(p*100))
+}
+
+// renderRecap formats recap totals as e.g. "12 ok • 3 changed • 0 failed",
+// highlighting changes and failures.
+func renderRecap(t ansible.HostRecap) string {
+	count := func(n int, label string, hot lipgloss.Style) string {
+		style := subtitleStyle
+		if n > 0 {
+			style = hot
+		}
+		return style.Render(fmt.Sprintf("%d %s", n, label))
+	}
+	parts := []string{
+		count(t.Ok, "ok", checkStyle),
+		count(t.Changed, "changed", warnStyle),
+		count(t.Failed, "failed", errorStyle),
+	}
+	if t.Unreachable > 0 {
+		parts = append(parts, count(t.Unreachable, "unreachable", errorStyle))
+	}
+	if t.Skipped > 0 {
+		parts = append(parts, count(t.Skipped, "skipped", subtitleStyle))
+	}
+	return strings.Join(parts, subtitleStyle.Render(" • "))
+}
+
+// parseBool reads a yes/no field; anything config.ParseBoolStrict doesn't
+// recognise counts as no.
 func parseBool(s string) bool {
-	s = strings.TrimSpace(strings.ToLower(s))
-	return s == "true" || s == "yes" || s == "y" || s == "1"
+	v, _ := config.ParseBoolStrict(s)
+	return v
+}
+
+// isTerminal reports whether f is an interactive terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
 }
 
 // --- Public entry points ---
 
 // Run launches the interactive TUI.
 func Run() {
-	p := tea.NewProgram(initialModel(), tea.WithAltScreen())
+	p := tea.NewProgram(initialModel(), tea.WithAltScreen(), tea.WithMouseCellMotion())
 	programRef = p
 	if _, err := p.Run(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -824,30 +2163,318 @@ func Run() {
 	}
 }
 
-// RunPlaybookCLI runs the playbook from CLI flags (non-TUI mode).
-func RunPlaybookCLI(cfg *config.Config, tags string, dryRun bool) {
-	fmt.Printf("Running setup for user: %s\n", cfg.Username)
+// CLIOptions are the `flux run` flags for RunPlaybookCLI. The embedded
+// RunOptions carry the ansible-playbook flags; ExtraVars are merged over the
+// config's vars, and an empty LogFile or VaultPassFile falls back to the
+// config. AnsibleDir, Forks and the become settings come from cfg.
+type CLIOptions struct {
+	ansible.RunOptions
+
+	Safe        bool // check mode first, apply only if that succeeds
+	Yes         bool // skip the confirmation before an apply
+	JSON        bool // human output to stderr, a runResult to stdout
+	Quiet       bool // only prompts, warnings and errors
+	ChangedOnly bool // hide the output of tasks that changed nothing
+	SinceLast   bool // dry run: drift only for roles changed since the last run
+}
 
-	if err := ansible.EnsureInstalled(); err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to install Ansible: %v\n", err)
+// RunPlaybookCLI runs the playbook from CLI flags (non-TUI mode) and exits
+// with the run's exit code when it isn't 0. A successful dry run that would
+// change something exits with ExitDrift.
+func RunPlaybookCLI(cfg *config.Config, opts CLIOptions) {
+	ansible.SetQuiet(opts.Quiet)
+	run := func() (int, error) {
+		if !opts.ChangedOnly {
+			return runPlaybookCLI(cfg, opts)
+		}
+		return runChangedOnly(func() (int, error) {
+			return runPlaybookCLI(cfg, opts)
+		})
+	}
+	if !opts.JSON {
+		// --step prompts need ansible on the terminal, so those runs aren't
+		// read for the recap and failed tasks (and report no drift)
+		if opts.Step && !opts.SinceLast {
+			if code, _ := run(); code != 0 {
+				os.Exit(code)
+			}
+			return
+		}
+		var captured bytes.Buffer
+		opts.Capture = &captured
+		last := loadLastRun()
+		code, _ := run()
+		if !opts.DryRun {
+			rememberCLIFailedRoles(opts.Tags, opts.SkipTags, captured.String(), code != 0)
+		}
+		if code == 0 {
+			rememberCLIRun(captured.String(), opts.DryRun)
+		}
+		if code == 0 && opts.SinceLast {
+			code = sinceLastCode(last, captured.String())
+		} else if code == 0 && opts.DryRun {
+			code = driftCode(captured.String(), opts.Quiet)
+		}
+		if code != 0 {
+			os.Exit(code)
+		}
+		return
+	}
+
+	start := time.Now()
+	stdout := os.Stdout
+	var captured bytes.Buffer
+	restore, err := redirectStdout(&captured, os.Stderr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+	code, runErr := run()
+	restore()
+	if !opts.DryRun {
+		rememberCLIFailedRoles(opts.Tags, opts.SkipTags, captured.String(), code != 0)
+	}
+	if code == 0 {
+		rememberCLIRun(captured.String(), opts.DryRun)
+	}
+
+	total := ansible.ParseRecap(captured.String()).Total()
+	result := runResult{
+		OK:          runErr == nil && total.Failed == 0 && total.Unreachable == 0,
+		Changed:     total.Changed,
+		Failed:      total.Failed,
+		Unreachable: total.Unreachable,
+		Tags:        splitTags(opts.Tags),
+		DryRun:      opts.DryRun,
+		DurationMS:  time.Since(start).Milliseconds(),
+	}
+	if runErr != nil {
+		result.Error = runErr.Error()
+	}
+	out, _ := json.Marshal(result)
+	fmt.Fprintln(stdout, string(out))
+	if code == 0 && !result.OK {
+		code = 1
+	}
+	if code == 0 && opts.DryRun && total.Changed > 0 {
+		code = ExitDrift
+	}
+	if code != 0 {
+		os.Exit(code)
+	}
+}
+
+// runResult is the summary printed by `flux run --json`.
+type runResult struct {
+	OK          bool     `json:"ok"`
+	Changed     int      `json:"changed"`
+	Failed      int      `json:"failed"`
+	Unreachable int      `json:"unreachable"`
+	Tags        []string `json:"tags"`
+	DryRun      bool     `json:"dry_run"`
+	DurationMS  int64    `json:"duration_ms"`
+	Error       string   `json:"error,omitempty"`
+}
+
+// splitTags turns a --tags value into a list, never nil so JSON shows [].
+func splitTags(tags string) []string {
+	list := []string{}
+	for _, t := range strings.Split(tags, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			list = append(list, t)
+		}
+	}
+	return list
+}
+
+// ExitDrift is the exit code of a successful dry run that found changes to
+// apply, so CI can tell "would change something" from "failed" (1).
+const ExitDrift = 2
+
+// driftCode returns ExitDrift if the recap in a dry run's output reports
+// changed tasks, and 0 otherwise. Unless quiet, it says which.
+func driftCode(output string, quiet bool) int {
+	changed := ansible.ParseRecap(output).Total().Changed
+	if changed == 0 {
+		return 0
+	}
+	if !quiet {
+		fmt.Printf("Drift detected: %d task(s) would change (exit code %d)\n", changed, ExitDrift)
+	}
+	return ExitDrift
+}
+
+// redirectStdout points os.Stdout at a pipe whose contents are copied to
+// display and into buf. With display set to stderr everything that would
+// normally be printed (including ansible's own output) is still visible but
+// stdout stays free for machine-readable output. The returned func restores
+// os.Stdout and waits for the copy to finish.
+func redirectStdout(buf *bytes.Buffer, display io.Writer) (func(), error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	orig := os.Stdout
+	os.Stdout = w
+	done := make(chan struct{})
+	go func() {
+		io.Copy(io.MultiWriter(display, buf), r)
+		close(done)
+	}()
+	return func() {
+		os.Stdout = orig
+		w.Close()
+		<-done
+		r.Close()
+	}, nil
+}
+
+// runChangedOnly calls run with os.Stdout filtered through
+// ansible.ChangedOnly, then reports how many unchanged tasks were hidden.
+func runChangedOnly(run func() (int, error)) (int, error) {
+	orig := os.Stdout
+	filter, finish := ansible.ChangedOnly(func(line string) { fmt.Fprintln(orig, line) })
+	r, w, err := os.Pipe()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1, err
+	}
+	os.Stdout = w
+	done := make(chan struct{})
+	go func() {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), 512*1024)
+		for scanner.Scan() {
+			filter(scanner.Text())
+		}
+		close(done)
+	}()
+
+	code, runErr := run()
+	os.Stdout = orig
+	w.Close()
+	<-done
+	r.Close()
+	if n := finish(); n > 0 {
+		fmt.Fprintf(orig, "(%d unchanged task(s) hidden by --changed-only)\n", n)
+	}
+	return code, runErr
+}
+
+// runPlaybookCLI does the work of RunPlaybookCLI, reporting problems on
+// stderr and returning the process exit code along with the error, if any.
+func runPlaybookCLI(cfg *config.Config, cli CLIOptions) (int, error) {
+	opts := cli.RunOptions
+	if !opts.DryRun && !cli.Yes && isTerminal(os.Stdin) {
+		roles := opts.Tags
+		if roles == "" {
+			roles = "all"
+		}
+		fmt.Printf("About to apply changes for user %s (roles: %s).\n", cfg.Username, roles)
+		fmt.Print("Continue? [y/N]: ")
+		line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if yes, ok := config.ParseBoolStrict(line); !ok || !yes {
+			fmt.Println("Aborted.")
+			return 1, errors.New("aborted by user")
+		}
+	}
+
+	unlock, err := config.Lock()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1, err
+	}
+	defer unlock()
+
+	if !cli.Quiet {
+		fmt.Printf("Running setup for user: %s\n", cfg.Username)
+	}
+
+	// Ctrl+C cancels the running apt command or playbook
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := ansible.EnsureInstalled(ctx, ansible.InstallMethod(cfg.AnsibleInstallMethod), cfg.InstallTimeoutDuration()); err != nil {
+		if errors.Is(err, ansible.ErrVersionTooOld) || errors.Is(err, ansible.ErrOfflineNotInstalled) {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		} else {
+			fmt.Fprintf(os.Stderr, "Failed to install Ansible: %v\n", err)
+		}
+		return 1, err
+	}
 
 	ansibleDir, err := ansible.FindAnsibleDir()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Cannot find ansible directory: %v\n", err)
-		os.Exit(1)
+		return 1, err
+	}
+
+	if excluded := cfg.DryRunSkipTags(opts.DryRun); excluded != "" && !cli.Quiet {
+		fmt.Printf("Note: skipping roles without check-mode support: %s\n", excluded)
+	}
+	opts.AnsibleDir = ansibleDir
+	opts.ExtraVars = config.MergeVars(cfg.ToExtraVars(), opts.ExtraVars)
+	opts.SkipTags = cfg.RunSkipTags(cli.SkipTags, opts.DryRun)
+	opts.Forks = cfg.Forks
+	if opts.LogFile == "" {
+		opts.LogFile = cfg.LogFile
+	}
+	if opts.VaultPassFile == "" {
+		opts.VaultPassFile = cfg.VaultPasswordFile
+	}
+	opts.NoBecomePass = cfg.NoBecomePass || ((opts.Connection == "" || opts.Connection == "local") && platform.PasswordlessSudo())
+	if opts.NoBecomePass && !cfg.NoBecomePass && !cli.Quiet && os.Getuid() != 0 {
+		fmt.Println("→ Passwordless sudo detected; not asking for the become password")
 	}
 
-	extraVars := cfg.ToExtraVars()
-	if err := ansible.RunPlaybook(ansibleDir, extraVars, tags, dryRun); err != nil {
+	// Safe mode: a broken role should fail the check, not a half-done apply
+	if cli.Safe && !opts.DryRun {
+		if !cli.Quiet {
+			fmt.Println("→ Safe mode, step 1/2: checking with a dry run first")
+		}
+		check := opts
+		check.SkipTags = cfg.RunSkipTags(cli.SkipTags, true)
+		check.DryRun, check.Step = true, false
+		if err := ansible.RunPlaybook(ctx, check); err != nil {
+			if ctx.Err() != nil {
+				fmt.Fprintln(os.Stderr, "\nRun cancelled")
+				return 130, ctx.Err()
+			}
+			fmt.Fprintf(os.Stderr, "\nCheck failed, nothing was applied: %v\n", err)
+			return 1, err
+		}
+		if !cli.Quiet {
+			fmt.Println("\n→ Safe mode, step 2/2: check passed, applying")
+		}
+	}
+	if err := ansible.RunPlaybook(ctx, opts); err != nil {
+		if ctx.Err() != nil {
+			fmt.Fprintln(os.Stderr, "\nRun cancelled")
+			return 130, ctx.Err()
+		}
 		fmt.Fprintf(os.Stderr, "\nPlaybook failed: %v\n", err)
-		os.Exit(1)
+		if errors.Is(err, ansible.ErrKilled) {
+			fmt.Fprintln(os.Stderr, ansible.MemoryHint)
+		}
+		return 1, err
 	}
 
-	if dryRun {
+	switch {
+	case cli.Quiet:
+	case opts.DryRun:
 		fmt.Println("\n✓ Dry run complete — no changes were applied")
-	} else {
+	default:
 		fmt.Println("\n✓ Setup complete!")
 	}
+
+	// The hook failing doesn't undo the apply, so it only gets a warning
+	if !opts.DryRun && cfg.PostRunHook != "" {
+		if !cli.Quiet {
+			fmt.Println()
+		}
+		if err := ansible.RunHook(ctx, cfg.PostRunHook, cfg.HookEnv(), func(line string) { fmt.Println(line) }); err != nil {
+			fmt.Fprintf(os.Stderr, "\nWarning: %v\n", err)
+		}
+	}
+	return 0, nil
 }
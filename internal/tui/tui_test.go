@@ -0,0 +1,129 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jaydubyaeey/flux/internal/config"
+)
+
+var enter = tea.KeyMsg{Type: tea.KeyEnter}
+
+// editModel returns a model on the config editor, ready to save.
+func editModel(firstRun bool) model {
+	m := model{screen: screenConfigEdit, firstRun: firstRun, cfg: config.DefaultConfig()}
+	m.cfg.Username = "jay"
+	m.initEditFields()
+	m.editDone = true
+	return m
+}
+
+func TestConfigEditSaveFailureStaysInEditor(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("FLUX_CONFIG", "")
+	// A regular file where the config's directory should be
+	blocker := filepath.Join(home, "blocker")
+	if err := os.WriteFile(blocker, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	config.SetFilePath(filepath.Join(blocker, "config.yaml"))
+	t.Cleanup(func() { config.SetFilePath("") })
+
+	for _, firstRun := range []bool{true, false} {
+		next, _ := editModel(firstRun).handleConfigEdit(enter)
+		m := next.(model)
+		if m.screen != screenConfigEdit || !m.editDone {
+			t.Errorf("firstRun=%v: screen = %v after a failed save, want the editor", firstRun, m.screen)
+		}
+		if m.firstRun != firstRun {
+			t.Errorf("firstRun cleared although nothing was saved")
+		}
+		if !strings.HasPrefix(m.message, "Error saving:") {
+			t.Errorf("firstRun=%v: message = %q, want the save error", firstRun, m.message)
+		}
+		if view := m.View(); !strings.Contains(view, "Error saving:") {
+			t.Errorf("firstRun=%v: the editor doesn't show the save error", firstRun)
+		}
+	}
+}
+
+func TestConfigEditSaveFirstRun(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("FLUX_CONFIG", "")
+	config.SetFilePath(filepath.Join(home, "config.yaml"))
+	t.Cleanup(func() { config.SetFilePath("") })
+
+	next, _ := editModel(true).handleConfigEdit(enter)
+	m := next.(model)
+	if m.screen != screenMain || m.firstRun {
+		t.Errorf("screen = %v, firstRun = %v after saving, want the main menu", m.screen, m.firstRun)
+	}
+	if !config.Exists() {
+		t.Error("no config on disk after saving")
+	}
+}
+
+func TestConfigEditBoolToggles(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	m := model{screen: screenConfigEdit, editFields: []editField{
+		{key: "username", value: "jay", kind: fieldString},
+		{key: "safe_mode", value: "false", kind: fieldBool},
+	}}
+	m.editCursor, m.editInput = 1, "false"
+
+	for i, want := range []string{"true", "false"} {
+		next, _ := m.handleConfigEdit(enter)
+		m = next.(model)
+		if got := m.editFields[1].value; got != want {
+			t.Errorf("after enter #%d safe_mode = %q, want %q", i+1, got, want)
+		}
+		if m.editCursor != 1 || m.editDone {
+			t.Errorf("enter on a toggle moved on (cursor %d, done %v)", m.editCursor, m.editDone)
+		}
+	}
+
+	next, _ := m.handleConfigEdit(tea.KeyMsg{Type: tea.KeySpace})
+	m = next.(model)
+	if m.editFields[1].value != "true" {
+		t.Errorf("space didn't toggle safe_mode")
+	}
+
+	// A toggle as the last field can still be left for the review
+	next, _ = m.handleConfigEdit(tea.KeyMsg{Type: tea.KeyDown})
+	m = next.(model)
+	if !m.editDone {
+		t.Error("↓ on the last field didn't finish editing")
+	}
+	if !m.editedConfig().SafeMode {
+		t.Error("the toggled value was lost")
+	}
+}
+
+func TestInitialSelection(t *testing.T) {
+	roles := []string{"base", "golang", "podman", "k9s"}
+	deselected := []string{"podman", "k9s"}
+	tests := []struct {
+		name  string
+		saved map[string]bool
+		want  []bool
+	}{
+		{"first launch uses default_deselected", nil, []bool{true, true, false, false}},
+		{"last run's picks win", map[string]bool{"base": true, "golang": false, "podman": true, "k9s": false}, []bool{true, false, true, false}},
+		{"new roles use default_deselected", map[string]bool{"base": false, "golang": true}, []bool{false, true, false, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sel := initialSelection(roles, deselected, tt.saved)
+			for i, r := range roles {
+				if sel[i] != tt.want[i] {
+					t.Errorf("%s selected = %v, want %v", r, sel[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package updater
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Install methods reported by DetectInstall.
+const (
+	MethodFlag      = "--dir"
+	MethodEnv       = "FLUX_INSTALL_DIR"
+	MethodInstallSh = "install.sh"
+	MethodClone     = "git clone"
+)
+
+// DetectInstall finds the flux checkout to update and reports how it was
+// installed. It looks, in order, at SetInstallDir, FLUX_INSTALL_DIR, the
+// default ~/.local/share/flux used by install.sh, and the checkout the
+// running executable was built in. An explicit --dir or FLUX_INSTALL_DIR
+// that isn't a checkout is an error rather than falling through, since the
+// user asked for it.
+func DetectInstall() (dir string, method string, err error) {
+	if installDirOverride != "" {
+		return explicitInstall(installDirOverride, MethodFlag)
+	}
+	if env := os.Getenv("FLUX_INSTALL_DIR"); env != "" {
+		return explicitInstall(env, MethodEnv)
+	}
+
+	home, _ := os.UserHomeDir()
+	def := filepath.Join(home, defaultInstallDir)
+	if isCheckout(def) {
+		return def, MethodInstallSh, nil
+	}
+	if clone := executableCheckout(); clone != "" {
+		return clone, MethodClone, nil
+	}
+	return "", "", fmt.Errorf("no flux checkout found at %s or around the flux binary — was it installed via install.sh? (set FLUX_INSTALL_DIR or pass --dir if it lives elsewhere)", def)
+}
+
+func explicitInstall(dir, method string) (string, string, error) {
+	if !isCheckout(dir) {
+		return "", "", fmt.Errorf("%s (from %s) is not a flux git checkout", dir, method)
+	}
+	return dir, method, nil
+}
+
+// isCheckout reports whether dir is a git clone of flux.
+func isCheckout(dir string) bool {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(dir, "cmd", "flux"))
+	return err == nil
+}
+
+// executableCheckout returns the flux checkout containing the running
+// binary, e.g. after `go build -o flux ./cmd/flux` in a manual clone, or ""
+// if it isn't in one. A few parent directories are searched so binaries
+// built into a subdirectory like bin/ are found too.
+func executableCheckout() string {
+	exe, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	dir := filepath.Dir(exe)
+	for i := 0; i < 3; i++ {
+		if isCheckout(dir) {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return ""
+}
@@ -0,0 +1,75 @@
+package updater
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// OutputFunc receives the progress lines of an update, one at a time.
+type OutputFunc func(line string)
+
+// printLine is the OutputFunc of the CLI entry points.
+func printLine(line string) {
+	fmt.Println(line)
+}
+
+// lineWriter is an io.Writer that passes each complete line written to it
+// to out. Flush sends whatever is left after the last newline.
+type lineWriter struct {
+	out OutputFunc
+	buf bytes.Buffer
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	for {
+		line, err := w.buf.ReadString('\n')
+		if err != nil {
+			// No newline yet; keep the partial line for the next write
+			w.buf.WriteString(line)
+			return len(p), nil
+		}
+		w.out(strings.TrimRight(line, "\r\n"))
+	}
+}
+
+func (w *lineWriter) Flush() {
+	if w.buf.Len() > 0 {
+		w.out(strings.TrimRight(w.buf.String(), "\r\n"))
+		w.buf.Reset()
+	}
+}
+
+// dotInterval is how often printDots shows that a silent step is still
+// running.
+const dotInterval = 3 * time.Second
+
+// printDots prints a dot every dotInterval until the returned func is
+// called, which ends the line if any dots were printed.
+func printDots() (stop func()) {
+	done := make(chan struct{})
+	finished := make(chan bool)
+	go func() {
+		ticker := time.NewTicker(dotInterval)
+		defer ticker.Stop()
+		printed := false
+		for {
+			select {
+			case <-ticker.C:
+				fmt.Print(".")
+				printed = true
+			case <-done:
+				finished <- printed
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		if <-finished {
+			fmt.Println()
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package updater
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const releasesAPI = "https://api.github.com/repos/jaydubyaeey/flux/releases"
+
+// ErrNoReleaseAsset is returned by UpdateFromRelease when the release has no
+// binary for the current GOOS/GOARCH.
+var ErrNoReleaseAsset = errors.New("no release asset for this platform")
+
+var httpClient = &http.Client{Timeout: 2 * time.Minute}
+
+type release struct {
+	TagName string         `json:"tag_name"`
+	Assets  []releaseAsset `json:"assets"`
+}
+
+type releaseAsset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// UpdateFromRelease downloads the prebuilt flux binary for this platform from
+// GitHub releases, verifies its SHA-256 checksum and atomically replaces
+// BinPath(). An empty tag means the latest release. Only the binary is
+// updated; the ansible/ checkout is left as-is.
+func UpdateFromRelease(tag string) error {
+	return updateFromRelease(tag, printLine)
+}
+
+// updateFromRelease does the work of UpdateFromRelease, reporting progress
+// through report.
+func updateFromRelease(tag string, report OutputFunc) error {
+	if offline {
+		return ErrOffline
+	}
+	report("→ Checking GitHub releases...")
+	rel, err := fetchRelease(tag)
+	if err != nil {
+		return err
+	}
+
+	asset, ok := findAsset(rel.Assets)
+	if !ok {
+		return fmt.Errorf("%w (%s/%s in %s)", ErrNoReleaseAsset, runtime.GOOS, runtime.GOARCH, rel.TagName)
+	}
+	want, err := expectedChecksum(rel.Assets, asset.Name)
+	if err != nil {
+		return err
+	}
+
+	report(fmt.Sprintf("→ Downloading %s (%s)...", asset.Name, rel.TagName))
+	data, err := download(asset.URL)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); got != want {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", asset.Name, want, got)
+	}
+
+	if strings.HasSuffix(asset.Name, ".tar.gz") || strings.HasSuffix(asset.Name, ".tgz") {
+		if data, err = extractBinary(data); err != nil {
+			return err
+		}
+	}
+
+	binPath := BinPath()
+	if err := backupBinary(); err != nil {
+		return err
+	}
+	if err := replaceFile(binPath, data); err != nil {
+		return err
+	}
+	report(fmt.Sprintf("✓ Updated to %s (%s)", rel.TagName, binPath))
+	return nil
+}
+
+func fetchRelease(tag string) (*release, error) {
+	url := releasesAPI + "/latest"
+	if tag != "" {
+		url = releasesAPI + "/tags/" + tag
+	}
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query releases: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to query releases: %s", resp.Status)
+	}
+	var rel release
+	if err := json.NewDecoder(resp.Body).Decode(&rel); err != nil {
+		return nil, fmt.Errorf("invalid release response: %w", err)
+	}
+	return &rel, nil
+}
+
+// findAsset picks the asset whose name mentions both GOOS and GOARCH,
+// ignoring checksum files.
+func findAsset(assets []releaseAsset) (releaseAsset, bool) {
+	for _, a := range assets {
+		name := strings.ToLower(a.Name)
+		if isChecksumFile(name) {
+			continue
+		}
+		if strings.Contains(name, runtime.GOOS) && strings.Contains(name, runtime.GOARCH) {
+			return a, true
+		}
+	}
+	return releaseAsset{}, false
+}
+
+func isChecksumFile(name string) bool {
+	return strings.HasSuffix(name, ".sha256") || strings.Contains(name, "checksums")
+}
+
+// expectedChecksum looks for "<asset>.sha256" or a checksums.txt listing and
+// returns the hex SHA-256 for name. A release without checksums is rejected.
+func expectedChecksum(assets []releaseAsset, name string) (string, error) {
+	for _, a := range assets {
+		if a.Name != name+".sha256" && !strings.Contains(strings.ToLower(a.Name), "checksums") {
+			continue
+		}
+		data, err := download(a.URL)
+		if err != nil {
+			return "", err
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) == 1 && a.Name == name+".sha256" {
+				return strings.ToLower(fields[0]), nil
+			}
+			if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+				return strings.ToLower(fields[0]), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no checksum published for %s; refusing to install", name)
+}
+
+func download(url string) ([]byte, error) {
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("download failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download %s failed: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// extractBinary returns the "flux" entry from a gzipped tarball.
+func extractBinary(archive []byte) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, fmt.Errorf("invalid archive: %w", err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("archive does not contain a flux binary")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid archive: %w", err)
+		}
+		if hdr.Typeflag == tar.TypeReg && filepath.Base(hdr.Name) == "flux" {
+			return io.ReadAll(tr)
+		}
+	}
+}
+
+// replaceFile writes data to a temp file beside path and renames it into
+// place, so path is never left half-written.
+func replaceFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".flux-new-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,82 @@
+package updater
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// retryAttempts is how many times a network git command is tried in total.
+var retryAttempts = 3
+
+// retryDelay is the wait before the first retry; it doubles each time.
+var retryDelay = 2 * time.Second
+
+// DisableRetry makes network git commands fail on the first error, for
+// scripted use where a quick, predictable failure is preferred.
+func DisableRetry() {
+	retryAttempts = 1
+}
+
+// networkErrors are fragments of git output that indicate a transient
+// network problem worth retrying. Anything else (e.g. a pull that can't
+// fast-forward) fails immediately.
+var networkErrors = []string{
+	"could not resolve host",
+	"connection timed out",
+	"connection reset",
+	"connection refused",
+	"failed to connect",
+	"operation timed out",
+	"unable to access",
+	"early eof",
+	"the remote end hung up",
+	"rpc failed",
+	"tls",
+	"gnutls",
+	"ssh: connect to host",
+	"temporary failure in name resolution",
+}
+
+// isNetworkError reports whether git output looks like a transient failure.
+func isNetworkError(output string) bool {
+	out := strings.ToLower(output)
+	for _, frag := range networkErrors {
+		if strings.Contains(out, frag) {
+			return true
+		}
+	}
+	return false
+}
+
+// gitRetry runs git in dir, retrying with exponential backoff while it fails
+// with a network-looking error. label names the step in the progress
+// messages sent to out. If stream is set, git's output is also sent to out
+// as it runs. The combined output of the last attempt is returned.
+func gitRetry(dir, label string, out OutputFunc, stream bool, args ...string) (string, error) {
+	delay := retryDelay
+	for attempt := 1; ; attempt++ {
+		var buf bytes.Buffer
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		var w io.Writer = &buf
+		lines := &lineWriter{out: out}
+		if stream {
+			w = io.MultiWriter(&buf, lines)
+		}
+		cmd.Stdout = w
+		cmd.Stderr = w
+		err := cmd.Run()
+		lines.Flush()
+		output := strings.TrimSpace(buf.String())
+		if err == nil || attempt >= retryAttempts || !isNetworkError(output) {
+			return output, err
+		}
+		out(fmt.Sprintf("→ git %s failed; retrying %s (%d/%d) in %s...", label, label, attempt+1, retryAttempts, delay))
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
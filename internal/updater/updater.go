@@ -1,11 +1,15 @@
 package updater
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 )
 
 const (
@@ -13,81 +17,320 @@ const (
 	defaultBinPath    = ".local/bin/flux"
 )
 
-// InstallDir returns the path where flux was cloned.
+// installDirOverride is set by SetInstallDir (flux update --dir).
+var installDirOverride string
+
+// SetInstallDir overrides the install directory for this process.
+func SetInstallDir(dir string) {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	installDirOverride = dir
+}
+
+// InstallDir returns the path where flux was cloned. It honors, in order,
+// SetInstallDir, FLUX_INSTALL_DIR and the default ~/.local/share/flux.
 func InstallDir() string {
+	if installDirOverride != "" {
+		return installDirOverride
+	}
+	if env := os.Getenv("FLUX_INSTALL_DIR"); env != "" {
+		return env
+	}
 	home, _ := os.UserHomeDir()
 	return filepath.Join(home, defaultInstallDir)
 }
 
-// BinPath returns the path to the flux binary.
+// BinPath returns the path to the flux binary: FLUX_BIN_PATH if set, else
+// the default ~/.local/bin/flux. When there is nothing at the default path
+// and the running binary was built in a flux checkout (a manual clone), it
+// is the running binary instead.
 func BinPath() string {
+	if env := os.Getenv("FLUX_BIN_PATH"); env != "" {
+		return env
+	}
 	home, _ := os.UserHomeDir()
-	return filepath.Join(home, defaultBinPath)
+	def := filepath.Join(home, defaultBinPath)
+	if _, err := os.Stat(def); os.IsNotExist(err) && executableCheckout() != "" {
+		if exe, err := os.Executable(); err == nil {
+			if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+				return resolved
+			}
+			return exe
+		}
+	}
+	return def
+}
+
+// stashLocalChanges is set by StashLocalChanges.
+var stashLocalChanges bool
+
+// StashLocalChanges makes Update stash uncommitted changes in the install
+// directory before pulling and restore them afterwards, instead of refusing
+// to update.
+func StashLocalChanges() {
+	stashLocalChanges = true
+}
+
+// ErrOffline is returned by Update, UpdateFromRelease and CheckForUpdate
+// after SetOffline.
+var ErrOffline = errors.New("updates are disabled in offline mode")
+
+// offline is set by SetOffline.
+var offline bool
+
+// SetOffline disables everything that needs the network. Rollback still
+// works since the previous binary is local.
+func SetOffline() {
+	offline = true
 }
 
-// Update pulls the latest changes from git and rebuilds the binary.
+// Update pulls the latest changes from git and rebuilds the binary,
+// printing its progress. A dot is printed every few seconds while the build
+// runs, since a cold module cache can take a minute.
 func Update() error {
-	dir := InstallDir()
+	start := time.Now()
+	return logUpdate(start, update(printLine, true))
+}
 
-	// Check the install directory exists
-	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
-		return fmt.Errorf("flux install directory not found at %s — was it installed via install.sh?", dir)
+// UpdateStreaming is Update for callers that show the progress themselves:
+// each step, and git's and the Go build's output, is sent line by line
+// through onOutput.
+func UpdateStreaming(onOutput OutputFunc) error {
+	start := time.Now()
+	return logUpdate(start, update(onOutput, false))
+}
+
+// logUpdate records in flux's log how an update started at start ended, and
+// returns err.
+func logUpdate(start time.Time, err error) error {
+	took := time.Since(start).Round(time.Millisecond).String()
+	if err != nil {
+		slog.Error("update failed", "duration", took, "error", err)
+	} else {
+		slog.Info("update finished", "duration", took)
 	}
+	return err
+}
 
-	// Git fetch and check for updates
-	fmt.Println("→ Checking for updates...")
-	fetch := exec.Command("git", "fetch", "--quiet")
-	fetch.Dir = dir
-	fetch.Stdout = os.Stdout
-	fetch.Stderr = os.Stderr
-	if err := fetch.Run(); err != nil {
-		return fmt.Errorf("git fetch failed: %w", err)
+// update does the work of Update. With dots, a dot is printed to stdout
+// every few seconds while the build runs.
+func update(report OutputFunc, dots bool) (err error) {
+	if offline {
+		return ErrOffline
+	}
+	dir, method, err := DetectInstall()
+	if err != nil {
+		return err
+	}
+	if method == MethodClone {
+		report("→ Updating the checkout at " + dir)
 	}
 
-	// Check if we're behind
-	status := exec.Command("git", "status", "-uno")
-	status.Dir = dir
-	out, err := status.Output()
+	// Without a Go toolchain we can't rebuild; try a prebuilt release instead
+	goPath, err := findGo()
 	if err != nil {
-		return fmt.Errorf("git status failed: %w", err)
+		report("→ Go not found; trying a prebuilt release binary...")
+		if relErr := updateFromRelease("", report); !errors.Is(relErr, ErrNoReleaseAsset) {
+			return relErr
+		}
+		return err
 	}
 
-	if strings.Contains(string(out), "Your branch is up to date") {
-		fmt.Println("✓ Already up to date")
+	report("→ Checking for updates...")
+	behind, _, err := checkForUpdate(report)
+	if err != nil {
+		return err
+	}
+	if behind == 0 {
+		report("✓ Already up to date")
 		return nil
 	}
 
+	// Local edits make --ff-only fail with a confusing message, so check first
+	dirty, err := gitOutput(dir, "status", "--porcelain", "--untracked-files=no")
+	if err != nil {
+		return fmt.Errorf("git status failed: %w\n%s", err, dirty)
+	}
+	if dirty != "" {
+		if !stashLocalChanges {
+			return fmt.Errorf("local changes in %s are blocking the update:\n%s\ncommit or discard them, or run 'flux update --stash' to set them aside during the update", dir, dirty)
+		}
+		report("→ Stashing local changes...")
+		if out, err := gitOutput(dir, "stash", "push", "-m", "flux update"); err != nil {
+			return fmt.Errorf("git stash failed: %w\n%s", err, out)
+		}
+		defer func() {
+			if popErr := popStash(dir, report); popErr != nil && err == nil {
+				err = popErr
+			}
+		}()
+	}
+
 	// Pull
-	fmt.Println("→ Pulling latest changes...")
-	pull := exec.Command("git", "pull", "--ff-only")
-	pull.Dir = dir
-	pull.Stdout = os.Stdout
-	pull.Stderr = os.Stderr
-	if err := pull.Run(); err != nil {
+	report("→ Pulling latest changes...")
+	if _, err := gitRetry(dir, "pull", report, true, "pull", "--ff-only"); err != nil {
 		return fmt.Errorf("git pull failed: %w", err)
 	}
 
-	// Rebuild
-	fmt.Println("→ Rebuilding...")
+	// Rebuild into a temp path so an interrupted or failed build never
+	// leaves a half-written binary in place.
+	report("→ Rebuilding (can take a minute on a fresh module cache)...")
 	binPath := BinPath()
+	if err := backupBinary(); err != nil {
+		return err
+	}
+
+	tmpPath := binPath + ".new"
+	defer os.Remove(tmpPath)
+	build := exec.Command(goPath, "build", "-ldflags", buildLdflags(dir), "-o", tmpPath, "./cmd/flux")
+	build.Dir = dir
+	buildOut := &lineWriter{out: report}
+	build.Stdout = buildOut
+	build.Stderr = buildOut
+	stopDots := func() {}
+	if dots {
+		stopDots = printDots()
+	}
+	buildErr := build.Run()
+	stopDots()
+	buildOut.Flush()
+	if buildErr != nil {
+		return fmt.Errorf("build failed: %w", buildErr)
+	}
+	if err := os.Rename(tmpPath, binPath); err != nil {
+		return fmt.Errorf("failed to install new binary: %w", err)
+	}
 
-	// Ensure Go is on PATH (may have been installed to /usr/local/go/bin)
-	goPath, err := exec.LookPath("go")
+	report(fmt.Sprintf("✓ Updated successfully (%s)", binPath))
+	return nil
+}
+
+// buildLdflags stamps the commit of dir and the current time into the
+// binary, for `flux version --full`.
+func buildLdflags(dir string) string {
+	rev, err := gitOutput(dir, "rev-parse", "--short=12", "HEAD")
 	if err != nil {
-		goPath = "/usr/local/go/bin/go"
-		if _, statErr := os.Stat(goPath); statErr != nil {
-			return fmt.Errorf("go not found on PATH or in /usr/local/go/bin — is Go installed?")
-		}
+		rev = "unknown"
 	}
+	return fmt.Sprintf("-X main.commit=%s -X main.buildDate=%s", rev, time.Now().UTC().Format(time.RFC3339))
+}
 
-	build := exec.Command(goPath, "build", "-o", binPath, "./cmd/flux")
-	build.Dir = dir
-	build.Stdout = os.Stdout
-	build.Stderr = os.Stderr
-	if err := build.Run(); err != nil {
-		return fmt.Errorf("build failed: %w", err)
+// popStash restores the changes stashed by Update. A conflicting pop leaves
+// the stash entry in place, so the error explains how to recover it.
+func popStash(dir string, report OutputFunc) error {
+	report("→ Restoring local changes...")
+	out, err := gitOutput(dir, "stash", "pop")
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("restoring your local changes conflicted with the update:\n%s\n"+
+		"Your changes are still saved in the stash (see 'git -C %s stash list').\n"+
+		"Resolve the conflicts in %s and run 'git stash drop', or discard the partial\n"+
+		"restore with 'git checkout -- .' and re-apply later with 'git stash pop'", out, dir, dir)
+}
+
+// PrevBinPath returns where the previous binary is kept for Rollback.
+func PrevBinPath() string {
+	return BinPath() + ".prev"
+}
+
+// backupBinary copies the current binary to PrevBinPath. A missing binary is
+// not an error (nothing to back up).
+func backupBinary() error {
+	data, err := os.ReadFile(BinPath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to back up binary: %w", err)
 	}
+	if err := os.WriteFile(PrevBinPath(), data, 0755); err != nil {
+		return fmt.Errorf("failed to back up binary: %w", err)
+	}
+	return nil
+}
 
-	fmt.Printf("✓ Updated successfully (%s)\n", binPath)
+// Rollback swaps the previous binary back into place. The replaced binary
+// becomes the new .prev, so running Rollback twice undoes it.
+func Rollback() error {
+	binPath, prevPath := BinPath(), PrevBinPath()
+	if _, err := os.Stat(prevPath); err != nil {
+		return fmt.Errorf("no previous binary at %s", prevPath)
+	}
+
+	swap := binPath + ".swap"
+	if err := os.Rename(binPath, swap); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Rename(prevPath, binPath); err != nil {
+		os.Rename(swap, binPath)
+		return err
+	}
+	if err := os.Rename(swap, prevPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	fmt.Printf("✓ Rolled back to previous binary (%s)\n", binPath)
 	return nil
 }
+
+// CheckForUpdate fetches from the upstream branch without pulling and reports
+// how many commits the install is behind and the subject of the newest one.
+func CheckForUpdate() (behind int, latestSubject string, err error) {
+	return checkForUpdate(printLine)
+}
+
+// checkForUpdate does the work of CheckForUpdate, reporting fetch retries
+// through report.
+func checkForUpdate(report OutputFunc) (behind int, latestSubject string, err error) {
+	if offline {
+		return 0, "", ErrOffline
+	}
+	dir, _, err := DetectInstall()
+	if err != nil {
+		return 0, "", err
+	}
+
+	if out, err := gitRetry(dir, "fetch", report, false, "fetch", "--quiet"); err != nil {
+		return 0, "", fmt.Errorf("git fetch failed: %w\n%s", err, out)
+	}
+
+	out, err := gitOutput(dir, "rev-list", "--count", "HEAD..@{u}")
+	if err != nil {
+		return 0, "", fmt.Errorf("git rev-list failed: %w\n%s", err, out)
+	}
+	behind, err = strconv.Atoi(out)
+	if err != nil {
+		return 0, "", fmt.Errorf("unexpected git rev-list output %q", out)
+	}
+	if behind == 0 {
+		return 0, "", nil
+	}
+
+	latestSubject, err = gitOutput(dir, "log", "-1", "--format=%s", "@{u}")
+	if err != nil {
+		return 0, "", fmt.Errorf("git log failed: %w\n%s", err, latestSubject)
+	}
+	return behind, latestSubject, nil
+}
+
+// gitOutput runs git in dir and returns its trimmed combined output.
+func gitOutput(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	return strings.TrimSpace(string(out)), err
+}
+
+// findGo locates the go binary, which may have been installed to
+// /usr/local/go/bin without being on PATH.
+func findGo() (string, error) {
+	if goPath, err := exec.LookPath("go"); err == nil {
+		return goPath, nil
+	}
+	goPath := "/usr/local/go/bin/go"
+	if _, err := os.Stat(goPath); err != nil {
+		return "", fmt.Errorf("go not found on PATH or in /usr/local/go/bin — is Go installed?")
+	}
+	return goPath, nil
+}
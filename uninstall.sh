@@ -1,8 +1,8 @@
 #!/usr/bin/env bash
 set -euo pipefail
 
-INSTALL_DIR="$HOME/.local/share/flux"
-BIN="$HOME/.local/bin/flux"
+INSTALL_DIR="${FLUX_INSTALL_DIR:-$HOME/.local/share/flux}"
+BIN="${FLUX_BIN_PATH:-$HOME/.local/bin/flux}"
 CONFIG_DIR="$HOME/.config/flux"
 
 echo ""